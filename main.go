@@ -1,34 +1,281 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samox73/ksh/pkg/cli"
+	"github.com/samox73/ksh/pkg/config"
 	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
 	"github.com/samox73/ksh/pkg/tea/views"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+	"k8s.io/klog/v2"
 )
 
 func main() {
-	init := views.BuildNamespaceModel()
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		showConfig(os.Args[2:])
+		return
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Println("Error reading configuration:", err)
+		os.Exit(2)
+	}
+	applyConfig(cfg)
+
+	if len(os.Args) > 1 && os.Args[1] == "cp" {
+		copyFiles(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "exec" {
+		execCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replay(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		history(os.Args[2:])
+		return
+	}
+
+	target, err := cli.ParseArgs("ksh", os.Args[1:], os.Stderr)
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Println("Error parsing arguments:", err)
+		os.Exit(2)
+	}
+
+	client, err := k8s.GetClient()
+	if err != nil {
+		fmt.Println("Error connecting to cluster:", err)
+		os.Exit(1)
+	}
+
+	if target.Pod != "" || target.Selector.String() != "" {
+		target, err = cli.Resolve(client, target)
+		if err != nil {
+			fmt.Println("Invalid target:", err)
+			os.Exit(1)
+		}
+		if target.Complete() {
+			run(client, target)
+			return
+		}
+	}
+
+	var init tea.Model
+	switch {
+	case target.AllNamespaces:
+		init = views.BuildSearchModel(client, target.Selector)
+	case target.Pod != "":
+		init = views.BuildContainerModelFor(client, target.Namespace, target.Pod)
+	case target.Namespace != "":
+		init = views.BuildPodModelFor(client, target.Namespace, target.Selector)
+	default:
+		init = views.BuildStartModel(client)
+	}
+
+	// client-go logs errors of background work such as port forwards to
+	// stderr, which would draw over the TUI.
+	klog.LogToStderr(false)
+	klog.SetOutput(io.Discard)
+
+	views.SetDefaultCommand(target.Command, target.TTY)
+	if _, err := tea.NewProgram(init, tea.WithAltScreen()).Run(); err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
+
+// run opens a shell in the target, or runs its command, and exits with the
+// remote exit code if it failed.
+func run(client k8s.Client, target cli.Target) {
+	session := k8s.Session{
+		Namespace: target.Namespace,
+		Pod:       target.Pod,
+		Container: target.Container,
+		Command:   target.Command,
+		TTY:       target.TTY,
+	}
+	if target.Stdin {
+		session.Stdin = os.Stdin
+	}
+	if err := k8s.Confirm(os.Stdin, os.Stderr, "open a session"); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if len(target.Command) == 0 {
+		fmt.Printf("Opening shell to %s\n", target)
+	}
+	err := session.Run(client)
+	if code := k8s.ExitCode(err); code > 0 {
+		os.Exit(code)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// copyFiles implements ksh cp.
+func copyFiles(args []string) {
+	copyArgs, err := cli.ParseCopyArgs("ksh cp", args, os.Stderr)
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Println("Error parsing arguments:", err)
+		os.Exit(2)
+	}
+	client, err := k8s.GetClient()
+	if err != nil {
+		fmt.Println("Error connecting to cluster:", err)
+		os.Exit(1)
+	}
+	if err := k8s.Confirm(os.Stdin, os.Stderr, "copy the files"); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if err := cli.Copy(client, copyArgs, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// execCommand implements ksh exec. It exits with 1 if the command failed in
+// any of the pods.
+func execCommand(args []string) {
+	execArgs, err := cli.ParseExecArgs("ksh exec", args, os.Stderr)
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Println("Error parsing arguments:", err)
+		os.Exit(2)
+	}
+	client, err := k8s.GetClient()
+	if err != nil {
+		fmt.Println("Error connecting to cluster:", err)
+		os.Exit(1)
+	}
+	if err := k8s.Confirm(os.Stdin, os.Stderr, "run the command"); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	results, err := cli.Exec(client, execArgs, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+// replay implements ksh replay.
+func replay(args []string) {
+	replayArgs, err := cli.ParseReplayArgs("ksh replay", args, os.Stderr)
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Println("Error parsing arguments:", err)
+		os.Exit(2)
+	}
+	if err := cli.Replay(replayArgs, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
 
-	model, err := tea.NewProgram(init, tea.WithAltScreen()).Run()
+// history implements ksh history. The entries are printed if --plain is set
+// or stdout is not a terminal, and listed in the TUI otherwise.
+func history(args []string) {
+	historyArgs, err := cli.ParseHistoryArgs("ksh history", args, os.Stderr)
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
+		fmt.Println("Error parsing arguments:", err)
+		os.Exit(2)
+	}
+	if historyArgs.Plain || !term.IsTerminal(int(os.Stdout.Fd())) {
+		entries, err := cli.History(historyArgs)
+		if err == nil {
+			err = cli.WriteHistory(os.Stdout, entries)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	client, err := k8s.GetClient()
+	if err != nil {
+		fmt.Println("Error connecting to cluster:", err)
+		os.Exit(1)
+	}
+	klog.LogToStderr(false)
+	klog.SetOutput(io.Discard)
+	if _, err := tea.NewProgram(views.BuildHistoryModel(client, historyArgs.Filter, historyArgs.Limit), tea.WithAltScreen()).Run(); err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
+}
 
-	result, ok := model.(views.ContainersModel)
-	if !ok {
-		fmt.Println("resulting model is invalid")
+// loadConfig reads the configuration file over the defaults of the cluster
+// connection and the TUI.
+func loadConfig() (config.Config, error) {
+	defaults := config.Defaults()
+	defaults.Theme = config.Theme(styles.DefaultTheme)
+	defaults.ListHeight = utils.ListHeight
+	return config.Load(defaults, views.ActionKeys)
+}
+
+// applyConfig makes the configuration take effect, in the TUI as well.
+func applyConfig(cfg config.Config) {
+	cfg.Apply()
+	views.HiddenNamespaces = cfg.HiddenNamespaces
+	views.KeyAliases = cfg.Keys
+	styles.ApplyTheme(styles.Theme(cfg.Theme))
+	utils.ListHeight = cfg.ListHeight
+}
+
+// showConfig implements ksh config. It exits with 1 if the configuration
+// file is invalid.
+func showConfig(args []string) {
+	configArgs, err := cli.ParseConfigArgs("ksh config", args, os.Stderr)
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Println("Error parsing arguments:", err)
+		os.Exit(2)
 	}
-	namespace := result.GetNamespace()
-	pod := result.GetPod()
-	container := result.GetContainer()
-	if pod != "" && namespace != "" && container != "" {
-		fmt.Printf("Opening shell to %s/%s/%s", namespace, pod, container)
-		k8s.OpenShell(result.GetClientset(), namespace, pod, container)
-	} else {
-		fmt.Println("invalid values")
+	if configArgs.Path {
+		fmt.Println(config.Path())
+		return
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading configuration:", err)
+		os.Exit(1)
+	}
+	if err := cfg.Write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
 }
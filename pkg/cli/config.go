@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/pflag"
+)
+
+// ConfigArgs are the arguments of the config subcommand.
+type ConfigArgs struct {
+	// Path prints where the configuration file is looked for instead.
+	Path bool
+}
+
+func ParseConfigArgs(name string, args []string, output io.Writer) (ConfigArgs, error) {
+	var a ConfigArgs
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags]\n\n", name)
+		fmt.Fprintln(output, "Prints the effective configuration, the defaults merged with the configuration file.")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&a.Path, "path", false, "print the location of the configuration file")
+	if err := fs.Parse(args); err != nil {
+		return a, err
+	}
+	if fs.NArg() > 0 {
+		return a, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	return a, nil
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samox73/ksh/pkg/format"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/spf13/pflag"
+)
+
+// CopyArgs are the arguments of the cp subcommand: a local path and a path
+// in the container of Target. Upload tells which of them is the source.
+type CopyArgs struct {
+	Target Target
+	Remote string
+	Local  string
+	Upload bool
+}
+
+// ParseCopyArgs reads a source and a destination, exactly one of which is a
+// remote path of the form [namespace/]pod[/container]:path.
+func ParseCopyArgs(name string, args []string, output io.Writer) (CopyArgs, error) {
+	var a CopyArgs
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags] SOURCE DESTINATION\n\n", name)
+		fmt.Fprintln(output, "One of SOURCE and DESTINATION is a path in a container: [namespace/]pod[/container]:path")
+		fs.PrintDefaults()
+	}
+	k8s.AddFlags(fs)
+	fs.StringVarP(&a.Target.Container, "container", "c", "", "name of the container to copy from or to")
+	addConfirmFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return a, err
+	}
+	if fs.NArg() != 2 {
+		return a, fmt.Errorf("expected a source and a destination, got %d arguments", fs.NArg())
+	}
+	a.Target.Namespace = k8s.ExplicitNamespace()
+
+	source, destination := fs.Arg(0), fs.Arg(1)
+	switch {
+	case isRemote(source) && isRemote(destination):
+		return a, fmt.Errorf("cannot copy between two containers")
+	case isRemote(source):
+		a.Local = destination
+		return a, parseRemote(&a, source)
+	case isRemote(destination):
+		a.Local, a.Upload = source, true
+		return a, parseRemote(&a, destination)
+	default:
+		return a, fmt.Errorf("expected one of %q and %q to be a path in a container", source, destination)
+	}
+}
+
+// isRemote tells container paths apart from local ones. Local paths that
+// contain a colon can be prefixed with ./ to be read as local.
+func isRemote(arg string) bool {
+	if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") {
+		return false
+	}
+	return strings.Index(arg, ":") > 0
+}
+
+func parseRemote(a *CopyArgs, arg string) error {
+	target, remote, _ := strings.Cut(arg, ":")
+	if remote == "" {
+		return fmt.Errorf("invalid path %q, expected a path after the colon", arg)
+	}
+	a.Remote = remote
+	parts := strings.Split(target, "/")
+	for _, part := range parts {
+		if part == "" {
+			return fmt.Errorf("invalid target %q, empty path segment", target)
+		}
+	}
+	switch len(parts) {
+	case 1:
+		a.Target.Pod = parts[0]
+	case 2, 3:
+		if a.Target.Namespace != "" && a.Target.Namespace != parts[0] {
+			return fmt.Errorf("target %q does not match namespace %s", target, a.Target.Namespace)
+		}
+		a.Target.Namespace, a.Target.Pod = parts[0], parts[1]
+		if len(parts) == 3 {
+			if a.Target.Container != "" && a.Target.Container != parts[2] {
+				return fmt.Errorf("target %q does not match container %s", target, a.Target.Container)
+			}
+			a.Target.Container = parts[2]
+		}
+	default:
+		return fmt.Errorf("invalid target %q, expected [namespace/]pod[/container]", target)
+	}
+	return nil
+}
+
+// Copy resolves the target and copies the files, printing the progress to
+// output.
+func Copy(client k8s.Client, a CopyArgs, output io.Writer) error {
+	target, err := Resolve(client, a.Target)
+	if err != nil {
+		return err
+	}
+	if !target.Complete() {
+		return fmt.Errorf("pod %s/%s has several containers, pick one with -c", target.Namespace, target.Pod)
+	}
+
+	p := &progressPrinter{output: output}
+	c := k8s.Copy{
+		Namespace: target.Namespace,
+		Pod:       target.Pod,
+		Container: target.Container,
+		Progress:  p.update,
+	}
+	if a.Upload {
+		err = c.Upload(client, a.Local, a.Remote)
+	} else {
+		err = c.Download(client, a.Remote, a.Local)
+	}
+	p.finish()
+	return err
+}
+
+// progressPrinter rewrites a single progress line at most every 100ms.
+type progressPrinter struct {
+	output  io.Writer
+	mu      sync.Mutex
+	last    time.Time
+	copied  int64
+	total   int64
+	printed bool
+}
+
+func (p *progressPrinter) update(copied int64, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.copied, p.total = copied, total
+	if time.Since(p.last) < 100*time.Millisecond {
+		return
+	}
+	p.last = time.Now()
+	p.print()
+}
+
+func (p *progressPrinter) print() {
+	line := format.ByteSize(p.copied) + " copied"
+	if p.total > 0 {
+		line = fmt.Sprintf("%s / %s copied (%d%%)", format.ByteSize(p.copied), format.ByteSize(p.total), min(100, p.copied*100/p.total))
+	}
+	fmt.Fprintf(p.output, "\r\033[K%s", line)
+	p.printed = true
+}
+
+func (p *progressPrinter) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.copied > 0 {
+		p.print()
+	}
+	if p.printed {
+		fmt.Fprintln(p.output)
+	}
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ExecArgs are the arguments of the exec subcommand, which runs a command in
+// several pods: the ones named in Pods or the ones matching Selector.
+type ExecArgs struct {
+	Namespace     string
+	AllNamespaces bool
+	Selector      k8s.PodSelector
+	Pods          []string
+	Container     string
+	Command       []string
+	Grouped       bool
+}
+
+func addBroadcastFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&k8s.BroadcastConcurrency, "concurrency", k8s.BroadcastConcurrency, "how many pods a command runs in at the same time when run in several pods")
+	fs.DurationVar(&k8s.BroadcastTimeout, "timeout", k8s.BroadcastTimeout, "how long a command may run in each pod when run in several pods")
+}
+
+func ParseExecArgs(name string, args []string, output io.Writer) (ExecArgs, error) {
+	var a ExecArgs
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags] (-l SELECTOR | POD...) -- COMMAND [ARGS...]\n", name)
+		fs.PrintDefaults()
+	}
+	k8s.AddFlags(fs)
+	fs.StringVarP(&a.Container, "container", "c", "", "container to run the command in, defaults to the only or first container of each pod")
+	selector := fs.StringP("selector", "l", "", "label or field selector of the pods, e.g. app=api,status.phase=Running")
+	fs.BoolVarP(&a.AllNamespaces, "all-namespaces", "A", false, "select pods in all namespaces")
+	fs.BoolVar(&a.Grouped, "group", false, "print the output of each pod in one piece once it is done, instead of prefixing every line")
+	addBroadcastFlags(fs)
+	addAuditFlags(fs)
+	addConfirmFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return a, err
+	}
+	a.Namespace = k8s.ExplicitNamespace()
+	var err error
+	if a.Selector, err = k8s.ParseSelector(*selector); err != nil {
+		return a, err
+	}
+
+	dash := fs.ArgsLenAtDash()
+	if dash < 0 || dash == fs.NArg() {
+		return a, fmt.Errorf("expected a command after --")
+	}
+	a.Pods, a.Command = fs.Args()[:dash], fs.Args()[dash:]
+	switch {
+	case len(a.Pods) == 0 && a.Selector.String() == "":
+		return a, fmt.Errorf("expected pods or a selector")
+	case len(a.Pods) > 0 && a.Selector.String() != "":
+		return a, fmt.Errorf("pods cannot be combined with --selector")
+	case len(a.Pods) > 0 && a.AllNamespaces:
+		return a, fmt.Errorf("pods cannot be combined with --all-namespaces")
+	case a.AllNamespaces && a.Namespace != "":
+		return a, fmt.Errorf("--all-namespaces cannot be combined with a namespace")
+	}
+	return a, nil
+}
+
+// Exec runs the command in the pods, writing their output and a summary of
+// the exit codes to output.
+func Exec(client k8s.Client, a ExecArgs, output io.Writer) ([]k8s.BroadcastResult, error) {
+	namespace := a.Namespace
+	if namespace == "" && !a.AllNamespaces {
+		var err error
+		if namespace, err = k8s.Namespace(); err != nil {
+			return nil, err
+		}
+	}
+	var pods []corev1.Pod
+	if len(a.Pods) > 0 {
+		for _, name := range a.Pods {
+			pod, err := client.Pod(namespace, name)
+			if err != nil {
+				return nil, err
+			}
+			pods = append(pods, *pod)
+		}
+	} else {
+		list, err := client.PodPage(namespace, a.Selector, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(list.Items) == 0 {
+			return nil, fmt.Errorf("no pods match %s", a.Selector)
+		}
+		pods = list.Items
+	}
+
+	results := k8s.Broadcast(client, k8s.BroadcastTargets(pods, a.Container), k8s.BroadcastOptions{
+		Command:     a.Command,
+		Concurrency: k8s.BroadcastConcurrency,
+		Timeout:     k8s.BroadcastTimeout,
+		Output:      output,
+		Grouped:     a.Grouped,
+	})
+	fmt.Fprintln(output)
+	k8s.WriteBroadcastSummary(output, results)
+	return results, nil
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/spf13/pflag"
+)
+
+// HistoryArgs are the arguments of the history subcommand. Limit keeps the
+// newest entries, all of them if it is not positive.
+type HistoryArgs struct {
+	Filter k8s.AuditFilter
+	Limit  int
+	Plain  bool
+}
+
+func addAuditFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&k8s.AuditLog, "audit-log", k8s.AuditLog, "file every shell and command is logged to, empty to disable logging")
+}
+
+func ParseHistoryArgs(name string, args []string, output io.Writer) (HistoryArgs, error) {
+	var a HistoryArgs
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags]\n\n", name)
+		fmt.Fprintln(output, "Lists the shells and commands of the audit log. --context, --namespace, --pod, --container and --os-user take patterns such as prod-*; the other kubectl flags apply to reconnecting from the TUI.")
+		fs.PrintDefaults()
+	}
+	addAuditFlags(fs)
+	// the TUI reconnects to the context of an entry, so the filters take the
+	// place of the kubectl --context and --namespace flags
+	k8s.AddConnectionFlags(fs)
+	fs.StringVar(&a.Filter.Context, "context", "", "only list sessions in matching kube contexts")
+	fs.StringVarP(&a.Filter.Namespace, "namespace", "n", "", "only list sessions in matching namespaces")
+	fs.StringVarP(&a.Filter.Pod, "pod", "p", "", "only list sessions in matching pods")
+	fs.StringVarP(&a.Filter.Container, "container", "c", "", "only list sessions in matching containers")
+	fs.StringVar(&a.Filter.User, "os-user", "", "only list sessions of matching local users")
+	since := fs.Duration("since", 0, "only list sessions started within this duration, e.g. 24h")
+	fs.BoolVar(&a.Filter.Failed, "failed", false, "only list sessions that failed or exited with a non-zero code")
+	fs.IntVar(&a.Limit, "limit", 100, "list at most this many of the newest sessions, 0 for all")
+	fs.BoolVar(&a.Plain, "plain", false, "print the sessions instead of opening the TUI")
+	if err := fs.Parse(args); err != nil {
+		return a, err
+	}
+	if fs.NArg() > 0 {
+		return a, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if *since > 0 {
+		a.Filter.Since = time.Now().Add(-*since)
+	}
+	return a, nil
+}
+
+// History returns the entries of the audit log selected by a, oldest first.
+func History(a HistoryArgs) ([]k8s.AuditEntry, error) {
+	entries, err := k8s.ReadAuditLog(a.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if a.Limit > 0 && len(entries) > a.Limit {
+		entries = entries[len(entries)-a.Limit:]
+	}
+	return entries, nil
+}
+
+// WriteHistory prints the entries as a table, oldest first.
+func WriteHistory(w io.Writer, entries []k8s.AuditEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tCONTEXT\tTARGET\tCOMMAND\tEXIT\tDURATION\tUSER")
+	for _, e := range entries {
+		command := strings.ReplaceAll(strings.Join(e.Command, " "), "\n", " ")
+		if e.Shell {
+			command = "shell"
+		}
+		exit := fmt.Sprint(e.ExitCode)
+		if e.ExitCode < 0 {
+			exit = e.Error
+		}
+		duration := time.Duration(e.Duration*1000) * time.Millisecond
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format(time.DateTime), e.Context, e.Target(), command, exit, duration, e.User)
+	}
+	return tw.Flush()
+}
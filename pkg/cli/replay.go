@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/spf13/pflag"
+)
+
+// ReplayArgs are the arguments of the replay subcommand. Without a path, the
+// recordings are listed instead.
+type ReplayArgs struct {
+	Path    string
+	Speed   float64
+	MaxWait time.Duration
+}
+
+func addRecordFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&k8s.Record, "record", k8s.Record, "record interactive sessions in asciicast format")
+	fs.StringSliceVar(&k8s.RecordContexts, "record-contexts", k8s.RecordContexts, "record interactive sessions in contexts matching these patterns, e.g. prod-*")
+	fs.StringVar(&k8s.RecordDir, "record-dir", k8s.RecordDir, "directory of the session recordings")
+}
+
+func ParseReplayArgs(name string, args []string, output io.Writer) (ReplayArgs, error) {
+	a := ReplayArgs{Speed: 1}
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags] [RECORDING]\n\n", name)
+		fmt.Fprintln(output, "Plays a session recording back, or lists the recordings if none is given.")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&k8s.RecordDir, "record-dir", k8s.RecordDir, "directory of the session recordings")
+	fs.Float64VarP(&a.Speed, "speed", "s", a.Speed, "playback speed")
+	fs.DurationVar(&a.MaxWait, "max-wait", 2*time.Second, "longest pause between two outputs, 0 keeps pauses as recorded")
+	if err := fs.Parse(args); err != nil {
+		return a, err
+	}
+	switch {
+	case fs.NArg() > 1:
+		return a, fmt.Errorf("expected at most one recording, got %d arguments", fs.NArg())
+	case a.Speed <= 0:
+		return a, fmt.Errorf("--speed must be positive")
+	}
+	a.Path = fs.Arg(0)
+	return a, nil
+}
+
+// Replay plays the recording back on output until it ends or ksh is
+// interrupted. A recording that is not found as given is looked up in the
+// directory of the recordings.
+func Replay(a ReplayArgs, output io.Writer) error {
+	if a.Path == "" {
+		return listRecordings(output)
+	}
+	file, err := os.Open(a.Path)
+	if os.IsNotExist(err) && !filepath.IsAbs(a.Path) {
+		file, err = os.Open(filepath.Join(k8s.RecordDir, a.Path))
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = k8s.Replay(file, k8s.ReplayOptions{Speed: a.Speed, MaxWait: a.MaxWait, Output: output, Context: ctx})
+	// reset the attributes and leave the alternate screen, in case the
+	// recording ended in the middle of a full screen program
+	fmt.Fprint(output, "\x1b[0m\x1b[?1049l\r\n")
+	if err == context.Canceled {
+		return nil
+	}
+	return err
+}
+
+func listRecordings(output io.Writer) error {
+	recordings, err := k8s.ListRecordings()
+	if err != nil {
+		return err
+	}
+	if len(recordings) == 0 {
+		fmt.Fprintf(output, "No recordings in %s\n", k8s.RecordDir)
+		return nil
+	}
+	tw := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tCONTEXT\tTARGET\tUSER\tRECORDING")
+	for _, r := range recordings {
+		context, target, user := "", r.Header.Title, ""
+		if s := r.Header.Session; s != nil {
+			context, target, user = s.Context, s.Namespace+"/"+s.Pod+"/"+s.Container, s.User
+		}
+		started := time.Unix(r.Header.Timestamp, 0).Format(time.DateTime)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", started, context, target, user, filepath.Base(r.Path))
+	}
+	return tw.Flush()
+}
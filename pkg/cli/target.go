@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/spf13/pflag"
+)
+
+type Target struct {
+	Namespace string
+	Pod       string
+	Container string
+	// Command is run instead of a shell if set.
+	Command []string
+	TTY     bool
+	Stdin   bool
+	// Selector narrows the pod list, see k8s.ParseSelector.
+	Selector k8s.PodSelector
+	// AllNamespaces lists the pods of all namespaces.
+	AllNamespaces bool
+}
+
+func (t Target) Complete() bool {
+	return t.Namespace != "" && t.Pod != "" && t.Container != ""
+}
+
+func (t Target) String() string {
+	parts := []string{t.Namespace}
+	if t.Pod != "" {
+		parts = append(parts, t.Pod)
+	}
+	if t.Container != "" {
+		parts = append(parts, t.Container)
+	}
+	return strings.Join(parts, "/")
+}
+
+func addConfirmFlags(fs *pflag.FlagSet) {
+	fs.BoolVarP(&k8s.AssumeYes, "yes", "y", false, "do not ask before acting in a protected context")
+}
+
+// ParseArgs reads the target from -n/-p/-c flags or from a single
+// positional argument of the form namespace[/pod[/container]], followed by an
+// optional command after "--". The kubectl connection flags are registered as
+// well and configure pkg/k8s.
+func ParseArgs(name string, args []string, output io.Writer) (Target, error) {
+	var t Target
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags] [namespace[/pod[/container]]] [-- command [args...]]\n", name)
+		fs.PrintDefaults()
+	}
+	k8s.AddFlags(fs)
+	fs.StringVarP(&t.Pod, "pod", "p", "", "name of the target pod")
+	fs.StringVarP(&t.Container, "container", "c", "", "name of the target container")
+	fs.BoolVarP(&t.TTY, "tty", "t", false, "allocate a TTY for the command, implies --stdin")
+	fs.BoolVarP(&t.Stdin, "stdin", "i", false, "pass local stdin to the command")
+	fs.BoolVarP(&t.AllNamespaces, "all-namespaces", "A", false, "list the pods of all namespaces")
+	selector := fs.StringP("selector", "l", "", "label or field selector of the listed pods, e.g. app=api,status.phase=Running")
+	fs.StringVar(&k8s.DebugImage, "debug-image", k8s.DebugImage, "image of the ephemeral containers started with the debug action")
+	addBroadcastFlags(fs)
+	addRecordFlags(fs)
+	addAuditFlags(fs)
+	addConfirmFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return t, err
+	}
+	t.Namespace = k8s.ExplicitNamespace()
+	var err error
+	if t.Selector, err = k8s.ParseSelector(*selector); err != nil {
+		return t, err
+	}
+
+	positional := fs.Args()
+	if dash := fs.ArgsLenAtDash(); dash >= 0 {
+		positional, t.Command = positional[:dash], positional[dash:]
+		if len(t.Command) == 0 {
+			return t, fmt.Errorf("expected a command after --")
+		}
+	}
+	if t.TTY {
+		t.Stdin = true
+	}
+	if (t.TTY || t.Stdin) && len(t.Command) == 0 {
+		return t, fmt.Errorf("--tty and --stdin require a command after --")
+	}
+
+	switch len(positional) {
+	case 0:
+	case 1:
+		if t.Namespace != "" || t.Pod != "" || t.Container != "" {
+			return t, fmt.Errorf("target %q cannot be combined with -n, -p or -c", positional[0])
+		}
+		parts := strings.Split(positional[0], "/")
+		if len(parts) > 3 {
+			return t, fmt.Errorf("invalid target %q, expected namespace[/pod[/container]]", positional[0])
+		}
+		for i, part := range parts {
+			if part == "" {
+				return t, fmt.Errorf("invalid target %q, empty path segment", positional[0])
+			}
+			switch i {
+			case 0:
+				t.Namespace = part
+			case 1:
+				t.Pod = part
+			case 2:
+				t.Container = part
+			}
+		}
+	default:
+		return t, fmt.Errorf("expected at most one target argument, got %d", len(positional))
+	}
+
+	if t.Container != "" && t.Pod == "" {
+		return t, fmt.Errorf("a container requires a pod")
+	}
+	if t.Pod != "" && t.Selector.String() != "" {
+		return t, fmt.Errorf("--selector cannot be combined with a pod")
+	}
+	if t.AllNamespaces && (t.Namespace != "" || t.Pod != "") {
+		return t, fmt.Errorf("--all-namespaces cannot be combined with a namespace or pod")
+	}
+	return t, nil
+}
+
+// Resolve checks that every part of the target exists in the cluster. A pod
+// or a selector without a namespace is looked up in the default namespace of
+// the context. If the pod only has a single container, it is filled in so the
+// shell can be opened right away.
+func Resolve(client k8s.Client, t Target) (Target, error) {
+	if t.AllNamespaces || (t.Pod == "" && t.Selector.String() == "") {
+		return t, nil
+	}
+	if t.Namespace == "" {
+		namespace, err := k8s.Namespace()
+		if err != nil {
+			return t, err
+		}
+		t.Namespace = namespace
+	}
+	if t.Pod == "" {
+		return t, nil
+	}
+
+	pods, err := client.Pods(t.Namespace)
+	if err != nil {
+		return t, err
+	}
+	found := false
+	for _, pod := range pods {
+		if pod.Name == t.Pod {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return t, fmt.Errorf("pod %s not found in namespace %s", t.Pod, t.Namespace)
+	}
+
+	containers, err := client.Containers(t.Namespace, t.Pod)
+	if err != nil {
+		return t, err
+	}
+	if t.Container == "" {
+		if name, ok := k8s.DefaultContainer(containers); ok {
+			t.Container = name
+		}
+		return t, nil
+	}
+	for _, container := range containers {
+		if container.Name == t.Container {
+			return t, nil
+		}
+	}
+	return t, fmt.Errorf("container %s not found in pod %s/%s", t.Container, t.Namespace, t.Pod)
+}
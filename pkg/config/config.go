@@ -0,0 +1,237 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/samox73/ksh/pkg/k8s"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Config is the configuration file of ksh. Settings missing from the file
+// keep their defaults, and command line flags take precedence over both.
+// Apply only sets those of the cluster connection; the TUI settings are
+// left to the caller, since this package does not depend on the TUI.
+type Config struct {
+	// Shells is the order in which shells are preferred, see k8s.Shells.
+	Shells     []string `yaml:"shells"`
+	DebugImage string   `yaml:"debugImage"`
+	// HiddenNamespaces are left out of the namespace list, e.g. kube-*.
+	HiddenNamespaces []string  `yaml:"hiddenNamespaces"`
+	Contexts         []Context `yaml:"contexts"`
+	// Keys binds keys to the actions of other keys, e.g. ctrl+d: D.
+	Keys  map[string]string `yaml:"keys"`
+	Theme Theme             `yaml:"theme"`
+	// ListHeight is the most rows of a list before the window size is
+	// known.
+	ListHeight int `yaml:"listHeight"`
+}
+
+// Theme holds the colours of ksh, as hex values such as #ff895e or ANSI
+// colour numbers such as 245. It has the fields of styles.Theme.
+type Theme struct {
+	Accent  string `yaml:"accent"`
+	Healthy string `yaml:"healthy"`
+	Warning string `yaml:"warning"`
+	Failing string `yaml:"failing"`
+	Muted   string `yaml:"muted"`
+}
+
+// Context holds the settings of the contexts whose name matches Name, a
+// pattern as in path.Match. The first match wins.
+type Context struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+	Protected bool   `yaml:"protected,omitempty"`
+	Record    bool   `yaml:"record,omitempty"`
+}
+
+// Path returns the location of the configuration file,
+// $XDG_CONFIG_HOME/ksh/config.yaml or ~/.config/ksh/config.yaml.
+func Path() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "ksh", "config.yaml")
+}
+
+// Defaults returns the cluster settings ksh uses without a file. The theme
+// and list height are left for the caller to fill in from the TUI.
+func Defaults() Config {
+	return Config{
+		Shells:     slices.Clone(k8s.Shells),
+		DebugImage: k8s.DebugImage,
+		Keys:       map[string]string{},
+	}
+}
+
+// Load reads the file at Path over defaults and validates the result, with
+// actionKeys as the keys that may be bound. A missing file is no error.
+func Load(defaults Config, actionKeys []string) (Config, error) {
+	c := defaults
+	file, err := os.Open(Path())
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	defer file.Close()
+	if err := c.decode(file); err != nil {
+		return c, inFile(file.Name(), err)
+	}
+	if err := c.Validate(actionKeys); err != nil {
+		return c, inFile(file.Name(), err)
+	}
+	return c, nil
+}
+
+// inFile prefixes each of the errors in err with the file name.
+func inFile(name string, err error) error {
+	lines := strings.Split(err.Error(), "\n")
+	for i := range lines {
+		lines[i] = name + ": " + lines[i]
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
+
+var unknownField = regexp.MustCompile(`field (\S+) not found in type \S+`)
+
+// decode reads YAML over c. Unknown settings are errors, since they are
+// most likely typos.
+func (c *Config) decode(r io.Reader) error {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	err := dec.Decode(c)
+	if err == io.EOF {
+		return nil
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		errs := make([]error, len(typeErr.Errors))
+		for i, e := range typeErr.Errors {
+			errs[i] = errors.New(unknownField.ReplaceAllString(e, "unknown setting $1"))
+		}
+		return errors.Join(errs...)
+	}
+	if err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	return nil
+}
+
+var (
+	shellName   = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+	colourValue = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+)
+
+// Validate reports all invalid settings at once. Keys may only be bound to
+// actionKeys.
+func (c Config) Validate(actionKeys []string) error {
+	var errs []error
+	fail := func(format string, a ...any) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	if len(c.Shells) == 0 {
+		fail("shells: at least one shell is needed")
+	}
+	for i, s := range c.Shells {
+		if s != "$SHELL" && !shellName.MatchString(s) {
+			fail("shells[%d]: %q is neither a shell name, a path nor $SHELL", i, s)
+		}
+	}
+	if c.DebugImage == "" {
+		fail("debugImage: must not be empty")
+	}
+	for i, pattern := range c.HiddenNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			fail("hiddenNamespaces[%d]: %q is not a valid pattern", i, pattern)
+		}
+	}
+	for i, ctx := range c.Contexts {
+		if _, err := path.Match(ctx.Name, ""); err != nil || ctx.Name == "" {
+			fail("contexts[%d].name: %q is not a valid pattern", i, ctx.Name)
+		}
+		if ctx.Namespace != "" {
+			if len(validation.IsDNS1123Label(ctx.Namespace)) > 0 {
+				fail("contexts[%d].namespace: %q is not a valid namespace, expected lower case letters, digits and dashes", i, ctx.Namespace)
+			}
+		}
+	}
+	keys := make([]string, 0, len(c.Keys))
+	for key := range c.Keys {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		action := c.Keys[key]
+		if key == "" {
+			fail("keys: a key must not be empty")
+		}
+		if !slices.Contains(actionKeys, action) {
+			fail("keys.%s: %q is not a key ksh acts on, expected one of %s", key, action, strings.Join(quoted(actionKeys), ", "))
+		}
+	}
+	colours := [][2]string{
+		{"accent", c.Theme.Accent},
+		{"healthy", c.Theme.Healthy},
+		{"warning", c.Theme.Warning},
+		{"failing", c.Theme.Failing},
+		{"muted", c.Theme.Muted},
+	}
+	for _, colour := range colours {
+		if n, err := strconv.Atoi(colour[1]); !colourValue.MatchString(colour[1]) && (err != nil || n < 0 || n > 255) {
+			fail("theme.%s: %q is not a colour, expected #rgb, #rrggbb or an ANSI colour from 0 to 255", colour[0], colour[1])
+		}
+	}
+	if c.ListHeight < 5 {
+		fail("listHeight: must be at least 5, got %d", c.ListHeight)
+	}
+	return errors.Join(errs...)
+}
+
+func quoted(keys []string) []string {
+	q := make([]string, len(keys))
+	for i, k := range keys {
+		q[i] = strconv.Quote(k)
+	}
+	return q
+}
+
+// Apply makes the cluster settings take effect. It must be called before
+// the command line is parsed, so that flags override it.
+func (c Config) Apply() {
+	k8s.Shells = c.Shells
+	k8s.DebugImage = c.DebugImage
+	for _, ctx := range c.Contexts {
+		k8s.ContextSettings = append(k8s.ContextSettings, k8s.ContextDefaults{Pattern: ctx.Name, Namespace: ctx.Namespace, Protected: ctx.Protected})
+		if ctx.Record {
+			k8s.RecordContexts = append(k8s.RecordContexts, ctx.Name)
+		}
+	}
+}
+
+// Write writes the configuration as YAML, in the format of the file.
+func (c Config) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
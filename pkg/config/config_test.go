@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		// errs are parts of the errors reported, none if the file is valid
+		errs []string
+	}{
+		{name: "valid", file: "shells: [zsh, /bin/sh]\nkeys:\n  ctrl+d: D\n  \"?\": enter\ntheme:\n  accent: \"#abc\"\n  muted: \"240\"\nlistHeight: 10\n"},
+		{name: "empty", file: ""},
+		{name: "unknown setting", file: "shell: [zsh]\n", errs: []string{"line 1: unknown setting shell"}},
+		{name: "unknown nested setting", file: "theme:\n  accnt: \"#abc\"\ncontexts:\n  - name: prod\n    protect: true\n", errs: []string{"line 2: unknown setting accnt", "line 5: unknown setting protect"}},
+		{name: "wrong type", file: "listHeight: many\n", errs: []string{"line 1: cannot unmarshal"}},
+		{name: "key bound to no action", file: "keys:\n  ctrl+d: delete\n  ctrl+e: E\n", errs: []string{`keys.ctrl+d: "delete" is not a key ksh acts on`, `keys.ctrl+e: "E" is not a key ksh acts on`}},
+		{name: "empty key", file: "keys:\n  \"\": D\n", errs: []string{"keys: a key must not be empty"}},
+		{name: "key bound twice", file: "keys:\n  ctrl+d: D\n  ctrl+d: enter\n", errs: []string{`line 3: mapping key "ctrl+d" already defined at line 2`}},
+		{name: "setting given twice", file: "debugImage: busybox\ndebugImage: alpine\n", errs: []string{`mapping key "debugImage" already defined`}},
+		{name: "invalid values", file: "shells: []\ndebugImage: \"\"\nhiddenNamespaces: [\"[\"]\ncontexts:\n  - name: prod\n    namespace: Payments\ntheme:\n  accent: red\n  muted: \"256\"\nlistHeight: 2\n", errs: []string{
+			"shells: at least one shell is needed",
+			"debugImage: must not be empty",
+			`hiddenNamespaces[0]: "[" is not a valid pattern`,
+			`contexts[0].namespace: "Payments" is not a valid namespace`,
+			`theme.accent: "red" is not a colour`,
+			`theme.muted: "256" is not a colour`,
+			"listHeight: must be at least 5, got 2",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", dir)
+			if err := os.MkdirAll(filepath.Join(dir, "ksh"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(Path(), []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			defaults := Defaults()
+			defaults.Theme = Theme{Accent: "#ff895e", Healthy: "#5fd75f", Warning: "#d7af5f", Failing: "#ff5f5f", Muted: "245"}
+			defaults.ListHeight = 20
+
+			_, err := Load(defaults, []string{"enter", "q", "D"})
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("loaded without errors, want %q", tt.errs)
+			}
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(tt.errs) {
+				t.Errorf("got %d errors, want %d:\n%v", len(lines), len(tt.errs), err)
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("errors do not report %q:\n%v", want, err)
+				}
+			}
+			for _, line := range lines {
+				if !strings.HasPrefix(line, Path()+": ") {
+					t.Errorf("error %q does not name the file", line)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadKeepsDefaults(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	defaults := Defaults()
+	defaults.ListHeight = 20
+	c, err := Load(defaults, nil)
+	if err != nil {
+		t.Fatalf("a missing file is no error: %v", err)
+	}
+	if c.ListHeight != 20 || c.DebugImage != defaults.DebugImage || len(c.Shells) != len(defaults.Shells) {
+		t.Errorf("got %+v, want the defaults %+v", c, defaults)
+	}
+}
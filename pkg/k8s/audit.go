@@ -0,0 +1,170 @@
+package k8s
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AuditLog is the file every shell and command is logged to, as a line of
+// JSON each. Nothing is logged if it is empty.
+var AuditLog = dataPath("audit.log")
+
+var auditMu sync.Mutex
+
+// AuditEntry is a shell or command run in a container.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Context   string    `json:"context"`
+	Server    string    `json:"server"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Container string    `json:"container"`
+	// Node is set for shells on a node, whose pod only exists for the
+	// session.
+	Node    string   `json:"node,omitempty"`
+	Command []string `json:"command"`
+	// Shell tells interactive shells apart from commands.
+	Shell bool `json:"shell"`
+	// Duration is in seconds.
+	Duration float64 `json:"duration"`
+	// ExitCode is -1 if the session failed without an exit code, see Error.
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+	User     string `json:"user"`
+}
+
+// Target names the container, or the node, the entry ran in.
+func (e AuditEntry) Target() string {
+	if e.Node != "" {
+		return "node/" + e.Node
+	}
+	return e.Namespace + "/" + e.Pod + "/" + e.Container
+}
+
+// dataPath returns the path of name in the directory where ksh keeps its
+// recordings and logs, $XDG_DATA_HOME/ksh or ~/.local/share/ksh.
+func dataPath(name string) string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "ksh", name)
+}
+
+func localUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// auditSession logs a session that began at start and ended with err. A log
+// that cannot be written is reported on stderr but does not fail the
+// session, which has already happened.
+func auditSession(e AuditEntry, start time.Time, err error) {
+	if AuditLog == "" {
+		return
+	}
+	e.Time = start
+	e.Duration = math.Round(time.Since(start).Seconds()*1000) / 1000
+	e.ExitCode = ExitCode(err)
+	if e.ExitCode < 0 {
+		e.Error = err.Error()
+	}
+	e.Context, _ = CurrentContext()
+	if config, err := restConfig(); err == nil {
+		e.Server = config.Host
+	}
+	e.User = localUser()
+	if err := appendAuditEntry(e); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing audit log:", err)
+	}
+}
+
+func appendAuditEntry(e AuditEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(AuditLog), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(AuditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// AuditFilter selects entries of the audit log. The strings are patterns as
+// in path.Match and, like the other fields, match everything if empty.
+type AuditFilter struct {
+	Context   string
+	Namespace string
+	Pod       string
+	Container string
+	User      string
+	Since     time.Time
+	Failed    bool
+}
+
+func (f AuditFilter) matches(e AuditEntry) bool {
+	patterns := [][2]string{
+		{f.Context, e.Context},
+		{f.Namespace, e.Namespace},
+		{f.Pod, e.Pod},
+		{f.Container, e.Container},
+		{f.User, e.User},
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p[0], p[1]); p[0] != "" && !ok {
+			return false
+		}
+	}
+	return !e.Time.Before(f.Since) && (!f.Failed || e.ExitCode != 0)
+}
+
+// ReadAuditLog returns the entries of the audit log that match the filter,
+// oldest first. Lines that are not entries, such as a line cut off by a
+// crash, are skipped.
+func ReadAuditLog(f AuditFilter) ([]AuditEntry, error) {
+	file, err := os.Open(AuditLog)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && f.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,240 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BroadcastConcurrency and BroadcastTimeout are the defaults for commands
+// run in several pods at once.
+var (
+	BroadcastConcurrency = 10
+	BroadcastTimeout     = 30 * time.Second
+)
+
+// BroadcastTarget is a container a broadcast command runs in.
+type BroadcastTarget struct {
+	Namespace string
+	Pod       string
+	Container string
+}
+
+func (t BroadcastTarget) String() string {
+	return t.Namespace + "/" + t.Pod + "/" + t.Container
+}
+
+// BroadcastTargets picks a container in each pod: the given one, or else the
+// pod's only regular container or its first one.
+func BroadcastTargets(pods []corev1.Pod, container string) []BroadcastTarget {
+	targets := make([]BroadcastTarget, 0, len(pods))
+	for i := range pods {
+		pod := &pods[i]
+		name := container
+		if name == "" {
+			var ok bool
+			if name, ok = DefaultContainer(PodContainers(pod)); !ok && len(pod.Spec.Containers) > 0 {
+				name = pod.Spec.Containers[0].Name
+			}
+		}
+		targets = append(targets, BroadcastTarget{Namespace: pod.Namespace, Pod: pod.Name, Container: name})
+	}
+	return targets
+}
+
+type BroadcastOptions struct {
+	Command []string
+	// Concurrency is how many targets run the command at the same time, all
+	// of them if it is not positive.
+	Concurrency int
+	// Timeout bounds the command in each target. The connection is closed
+	// once it passes, which leaves the command to the container runtime.
+	Timeout time.Duration
+	// Output receives the output of all targets. Each line is prefixed with
+	// its pod, or, if Grouped is set, the output of a target is written in
+	// one piece once it is done.
+	Output  io.Writer
+	Grouped bool
+}
+
+// BroadcastResult is the outcome of a broadcast command in one target.
+type BroadcastResult struct {
+	Target BroadcastTarget
+	// ExitCode is -1 if the command did not run to completion.
+	ExitCode int
+	Err      error
+	Duration time.Duration
+}
+
+// Broadcast runs a command in all targets and returns the results in the
+// order of the targets.
+func Broadcast(client Client, targets []BroadcastTarget, o BroadcastOptions) []BroadcastResult {
+	concurrency := o.Concurrency
+	if concurrency <= 0 || concurrency > len(targets) {
+		concurrency = len(targets)
+	}
+	prefixes := broadcastPrefixes(targets)
+	results := make([]BroadcastResult, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for i, target := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, target BroadcastTarget) {
+			defer func() { <-slots; wg.Done() }()
+			stdout := &prefixWriter{mu: &mu, w: o.Output, prefix: prefixes[i] + " "}
+			var group bytes.Buffer
+			if o.Grouped {
+				stdout = &prefixWriter{mu: &sync.Mutex{}, w: &group}
+			}
+			stderr := &prefixWriter{mu: stdout.mu, w: stdout.w, prefix: stdout.prefix}
+			results[i] = runBroadcast(client, target, o, stdout, stderr)
+			stdout.flush()
+			stderr.flush()
+			if o.Grouped {
+				mu.Lock()
+				fmt.Fprintf(o.Output, "=== %s (%s)\n", strings.TrimSpace(prefixes[i]), results[i].Status())
+				_, _ = group.WriteTo(o.Output)
+				mu.Unlock()
+			}
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+func runBroadcast(client Client, target BroadcastTarget, o BroadcastOptions, stdout io.Writer, stderr io.Writer) BroadcastResult {
+	ctx := context.Background()
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	err := client.Run(target.Namespace, target.Pod, target.Container, RunOptions{
+		Command: o.Command,
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Context: ctx,
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", o.Timeout)
+	}
+	auditSession(AuditEntry{Namespace: target.Namespace, Pod: target.Pod, Container: target.Container, Command: o.Command}, start, err)
+	return BroadcastResult{Target: target, ExitCode: ExitCode(err), Err: err, Duration: time.Since(start)}
+}
+
+// Status describes the outcome in a few words.
+func (r BroadcastResult) Status() string {
+	switch {
+	case r.Err == nil:
+		return "exit code 0"
+	case r.ExitCode > 0:
+		return fmt.Sprintf("exit code %d", r.ExitCode)
+	default:
+		return r.Err.Error()
+	}
+}
+
+// WriteBroadcastSummary writes a table of the results, followed by a count
+// of the targets the command failed in.
+func WriteBroadcastSummary(w io.Writer, results []BroadcastResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POD\tCONTAINER\tEXIT\tDURATION\tERROR")
+	targets := make([]BroadcastTarget, len(results))
+	for i, r := range results {
+		targets[i] = r.Target
+	}
+	names := podNames(targets)
+	failed := 0
+	for i, r := range results {
+		exit, message := fmt.Sprint(r.ExitCode), ""
+		if r.ExitCode < 0 {
+			exit, message = "-", r.Err.Error()
+		}
+		if r.Err != nil {
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", names[i], r.Target.Container, exit, r.Duration.Round(time.Millisecond), message)
+	}
+	_ = tw.Flush()
+	fmt.Fprintf(w, "%d of %d succeeded\n", len(results)-failed, len(results))
+}
+
+// podNames names the targets by pod, adding the namespace if they span
+// several namespaces.
+func podNames(targets []BroadcastTarget) []string {
+	namespaces := map[string]bool{}
+	for _, t := range targets {
+		namespaces[t.Namespace] = true
+	}
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.Pod
+		if len(namespaces) > 1 {
+			names[i] = t.Namespace + "/" + t.Pod
+		}
+	}
+	return names
+}
+
+// broadcastPrefixes brackets the pod names and pads them to the same width.
+func broadcastPrefixes(targets []BroadcastTarget) []string {
+	prefixes := podNames(targets)
+	width := 0
+	for _, p := range prefixes {
+		width = max(width, len(p)+2)
+	}
+	for i, p := range prefixes {
+		prefixes[i] = fmt.Sprintf("%-*s", width, "["+p+"]")
+	}
+	return prefixes
+}
+
+// prefixWriter writes complete lines to w, each starting with prefix, while
+// holding mu so that lines of concurrent writers do not mix.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	if i := bytes.LastIndexByte(p.buf, '\n'); i >= 0 {
+		p.write(p.buf[:i+1])
+		p.buf = append(p.buf[:0], p.buf[i+1:]...)
+	}
+	return len(b), nil
+}
+
+// flush writes what is left after the last newline.
+func (p *prefixWriter) flush() {
+	if len(p.buf) > 0 {
+		p.write(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) write(lines []byte) {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			out.WriteString(p.prefix)
+			out.Write(line)
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = out.WriteTo(p.w)
+}
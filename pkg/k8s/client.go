@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Client is the view of a cluster the TUI works against. It is implemented
+// by a real clientset through NewClient and by fake clusters in pkg/k8s/fake.
+type Client interface {
+	Namespaces() ([]corev1.Namespace, error)
+	Pods(namespace string) ([]corev1.Pod, error)
+	WatchPods(namespace string, selector PodSelector) (*PodWatcher, error)
+	PodPage(namespace string, selector PodSelector, limit int64, continueToken string) (*corev1.PodList, error)
+	Workloads(namespace string) ([]Workload, error)
+	Controller(namespace string, owner metav1.OwnerReference) (*metav1.OwnerReference, error)
+	Pod(namespace string, pod string) (*corev1.Pod, error)
+	Containers(namespace string, pod string) ([]PodContainer, error)
+	Exec(namespace string, pod string, container string, command []string) error
+	Run(namespace string, pod string, container string, options RunOptions) error
+	Debug(namespace string, pod string, target string, image string) (string, error)
+	Attach(namespace string, pod string, container string) error
+	Logs(namespace string, pod string, options LogOptions) (io.ReadCloser, error)
+	PortForward(namespace string, pod string, localPort int, remotePort int) (*Forward, error)
+	Nodes() ([]corev1.Node, error)
+	NodeShell(node string) error
+}
+
+type clusterClient struct {
+	clientset kubernetes.Interface
+	config    *rest.Config
+}
+
+// NewClient wraps a clientset. The rest config is used to open exec
+// connections and may be nil for clients that never exec.
+func NewClient(clientset kubernetes.Interface, config *rest.Config) Client {
+	return &clusterClient{clientset: clientset, config: config}
+}
+
+func GetClient() (Client, error) {
+	c, err := GetKubernetesClientset()
+	if err != nil {
+		return nil, err
+	}
+	config, err := restConfig()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c, config), nil
+}
+
+func (c *clusterClient) Namespaces() ([]corev1.Namespace, error) {
+	namespaces, err := GetNamespaces(c.clientset)
+	if err != nil {
+		return nil, err
+	}
+	return namespaces.Items, nil
+}
+
+func (c *clusterClient) Pods(namespace string) ([]corev1.Pod, error) {
+	pods, err := GetPods(c.clientset, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+func (c *clusterClient) WatchPods(namespace string, selector PodSelector) (*PodWatcher, error) {
+	return watchPods(c.clientset, namespace, selector)
+}
+
+func (c *clusterClient) PodPage(namespace string, selector PodSelector, limit int64, continueToken string) (*corev1.PodList, error) {
+	return ListPodPage(c.clientset, namespace, selector, limit, continueToken)
+}
+
+func (c *clusterClient) Workloads(namespace string) ([]Workload, error) {
+	return GetWorkloads(c.clientset, namespace)
+}
+
+func (c *clusterClient) Controller(namespace string, owner metav1.OwnerReference) (*metav1.OwnerReference, error) {
+	return GetController(c.clientset, namespace, owner)
+}
+
+func (c *clusterClient) Pod(namespace string, pod string) (*corev1.Pod, error) {
+	return GetPod(c.clientset, namespace, pod)
+}
+
+func (c *clusterClient) Containers(namespace string, pod string) ([]PodContainer, error) {
+	return GetContainers(c.clientset, namespace, pod)
+}
+
+func (c *clusterClient) Exec(namespace string, pod string, container string, command []string) error {
+	return openSpecificShell(c.clientset, c.config, namespace, pod, container, command, nil)
+}
+
+func (c *clusterClient) Run(namespace string, pod string, container string, options RunOptions) error {
+	return runCommand(c.clientset, c.config, namespace, pod, container, options)
+}
+
+func (c *clusterClient) Debug(namespace string, pod string, target string, image string) (string, error) {
+	return CreateDebugContainer(c.clientset, namespace, pod, target, image)
+}
+
+func (c *clusterClient) Attach(namespace string, pod string, container string) error {
+	return AttachDebugContainer(c.clientset, c.config, namespace, pod, container)
+}
+
+func (c *clusterClient) Logs(namespace string, pod string, options LogOptions) (io.ReadCloser, error) {
+	return StreamLogs(c.clientset, namespace, pod, options)
+}
+
+func (c *clusterClient) PortForward(namespace string, pod string, localPort int, remotePort int) (*Forward, error) {
+	return StartForward(c.clientset, c.config, namespace, pod, localPort, remotePort)
+}
+
+func (c *clusterClient) Nodes() ([]corev1.Node, error) {
+	nodes, err := GetNodes(c.clientset)
+	if err != nil {
+		return nil, err
+	}
+	return nodes.Items, nil
+}
+
+func (c *clusterClient) NodeShell(node string) error {
+	return openNodeShell(c.clientset, c.config, node)
+}
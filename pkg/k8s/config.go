@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	"github.com/spf13/pflag"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// configFlags is the single source of cluster configuration for ksh. It
+// honours KUBECONFIG, the standard kubectl flags and in-cluster config. It is
+// not persistent, so a context picked at runtime takes effect on the next
+// load.
+var configFlags = genericclioptions.NewConfigFlags(false)
+
+// AddFlags registers the kubectl connection flags (--kubeconfig, --context,
+// --namespace, --server, ...) on the given flag set.
+func AddFlags(fs *pflag.FlagSet) {
+	configFlags.AddFlags(fs)
+}
+
+// AddConnectionFlags registers the flags of AddFlags except --context and
+// --namespace, for commands that reach the contexts and namespaces they
+// work in some other way.
+func AddConnectionFlags(fs *pflag.FlagSet) {
+	all := pflag.NewFlagSet("", pflag.ContinueOnError)
+	configFlags.AddFlags(all)
+	all.VisitAll(func(f *pflag.Flag) {
+		if f.Name != "context" && f.Name != "namespace" {
+			fs.AddFlag(f)
+		}
+	})
+}
+
+// Namespace returns the namespace set with --namespace, or the default
+// namespace of the current context, see ContextSettings.
+func Namespace() (string, error) {
+	namespace, overridden, err := configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return "", wrapError("loading kubeconfig", err)
+	}
+	if !overridden {
+		if configured := contextNamespace(); configured != "" {
+			return configured, nil
+		}
+	}
+	return namespace, nil
+}
+
+// contextNamespace returns the namespace configured for the current context
+// in ContextSettings, if any.
+func contextNamespace() string {
+	context, err := CurrentContext()
+	if err != nil {
+		return ""
+	}
+	d, _ := contextDefaults(context)
+	return d.Namespace
+}
+
+// ExplicitNamespace returns the namespace set with --namespace, if any.
+func ExplicitNamespace() string {
+	if configFlags.Namespace == nil {
+		return ""
+	}
+	return *configFlags.Namespace
+}
+
+func restConfig() (*rest.Config, error) {
+	config, err := configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, wrapError("loading kubeconfig", err)
+	}
+	return config, nil
+}
+
+func rawConfig() (clientcmdapi.Config, error) {
+	config, err := configFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return config, wrapError("loading kubeconfig", err)
+	}
+	return config, nil
+}
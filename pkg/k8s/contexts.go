@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type KubeContext struct {
+	Name      string
+	Cluster   string
+	User      string
+	Namespace string
+	Current   bool
+}
+
+func GetContexts() ([]KubeContext, error) {
+	config, err := rawConfig()
+	if err != nil {
+		return nil, err
+	}
+	current, err := CurrentContext()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]KubeContext, 0, len(config.Contexts))
+	for name, c := range config.Contexts {
+		out = append(out, KubeContext{
+			Name:      name,
+			Cluster:   c.Cluster,
+			User:      c.AuthInfo,
+			Namespace: c.Namespace,
+			Current:   name == current,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// CurrentContext returns the context set with --context or picked in the
+// contexts view, falling back to the current-context of the kubeconfig.
+func CurrentContext() (string, error) {
+	if *configFlags.Context != "" {
+		return *configFlags.Context, nil
+	}
+	config, err := rawConfig()
+	if err != nil {
+		return "", err
+	}
+	return config.CurrentContext, nil
+}
+
+// UseContext switches the clientset and the exec configuration to the given
+// context for the rest of the session. The kubeconfig file is not modified.
+func UseContext(name string) (Client, error) {
+	config, err := rawConfig()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := config.Contexts[name]; !ok {
+		return nil, &Error{Kind: KindNotFound, Op: "switching context", Err: fmt.Errorf("context %q does not exist", name)}
+	}
+	previousContext, previousClientset := *configFlags.Context, clientset
+	*configFlags.Context, clientset = name, nil
+	client, err := GetClient()
+	if err != nil {
+		*configFlags.Context, clientset = previousContext, previousClientset
+		return nil, err
+	}
+	return client, nil
+}
+
+// SaveCurrentContext persists name as the current-context in the kubeconfig
+// file it was loaded from.
+func SaveCurrentContext(name string) error {
+	loader := configFlags.ToRawKubeConfigLoader()
+	config, err := loader.RawConfig()
+	if err != nil {
+		return wrapError("loading kubeconfig", err)
+	}
+	config.CurrentContext = name
+	if err := clientcmd.ModifyConfig(loader.ConfigAccess(), config, true); err != nil {
+		return wrapError("saving kubeconfig", err)
+	}
+	return nil
+}
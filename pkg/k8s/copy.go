@@ -0,0 +1,368 @@
+package k8s
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Copy copies files and directories between the local machine and a
+// container by streaming a tar archive through exec, like kubectl cp does.
+// Containers without tar can still copy single files, which are streamed
+// through cat instead.
+type Copy struct {
+	Namespace string
+	Pod       string
+	Container string
+	// Progress, if set, is called with the bytes copied so far and the total,
+	// which is -1 if it is not known up front.
+	Progress func(copied int64, total int64)
+}
+
+// Download copies remotePath out of the container to localPath, or into it
+// if localPath is an existing directory.
+func (c Copy) Download(client Client, remotePath string, localPath string) error {
+	remotePath = path.Clean(remotePath)
+	base := path.Base(remotePath)
+	dest := localPath
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		dest = filepath.Join(localPath, base)
+	}
+
+	reader, writer := io.Pipe()
+	var stderr bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		err := client.Run(c.Namespace, c.Pod, c.Container, RunOptions{
+			Command: []string{"tar", "cf", "-", "-C", path.Dir(remotePath), base},
+			Stdout:  writer,
+			Stderr:  &stderr,
+		})
+		writer.CloseWithError(err)
+		done <- err
+	}()
+	err := untar(reader, base, dest, &progress{total: -1, report: c.Progress})
+	if err == nil {
+		// Read the padding after the end of the archive, tar only exits
+		// once it is written.
+		_, err = io.Copy(io.Discard, reader)
+	}
+	reader.CloseWithError(err)
+
+	if runErr := <-done; runErr != nil {
+		if errors.Is(runErr, ErrExecutableNotFound) {
+			return c.downloadFile(client, remotePath, dest)
+		}
+		return commandError("copying "+remotePath, runErr, &stderr)
+	}
+	if err != nil {
+		return &Error{Kind: KindUnknown, Op: "copying " + remotePath, Err: err}
+	}
+	return nil
+}
+
+// downloadFile copies a single file with cat, for containers without tar.
+func (c Copy) downloadFile(client Client, remotePath string, dest string) error {
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	err = client.Run(c.Namespace, c.Pod, c.Container, RunOptions{
+		Command: []string{"cat", remotePath},
+		Stdout:  c.writer(file, -1),
+		Stderr:  &stderr,
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest)
+		if strings.Contains(stderr.String(), "Is a directory") {
+			return c.noTar(remotePath)
+		}
+		return commandError("copying "+remotePath, err, &stderr)
+	}
+	return nil
+}
+
+// Upload copies localPath into the container to remotePath, or into it if
+// remotePath is an existing directory.
+func (c Copy) Upload(client Client, localPath string, remotePath string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	total, err := localSize(localPath)
+	if err != nil {
+		return err
+	}
+	remotePath = path.Clean(remotePath)
+	dir, base := path.Dir(remotePath), path.Base(remotePath)
+	if IsDir(client, c.Namespace, c.Pod, c.Container, remotePath) {
+		dir, base = remotePath, filepath.Base(localPath)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeTar(writer, localPath, base, &progress{total: total, report: c.Progress}))
+	}()
+	var stderr bytes.Buffer
+	err = client.Run(c.Namespace, c.Pod, c.Container, RunOptions{
+		Command: []string{"tar", "xf", "-", "-C", dir},
+		Stdin:   reader,
+		Stderr:  &stderr,
+	})
+	reader.Close()
+	if err != nil {
+		if errors.Is(err, ErrExecutableNotFound) {
+			if info.IsDir() {
+				return c.noTar(localPath)
+			}
+			return c.uploadFile(client, localPath, path.Join(dir, base), total)
+		}
+		return commandError("copying "+remotePath, err, &stderr)
+	}
+	return nil
+}
+
+// uploadFile copies a single file with cat, for containers without tar.
+func (c Copy) uploadFile(client Client, localPath string, remotePath string, total int64) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var stderr bytes.Buffer
+	err = client.Run(c.Namespace, c.Pod, c.Container, RunOptions{
+		Command: []string{"sh", "-c", `cat > "$1"`, "sh", remotePath},
+		Stdin:   c.reader(file, total),
+		Stderr:  &stderr,
+	})
+	if errors.Is(err, ErrExecutableNotFound) {
+		return &Error{
+			Kind: KindExecutableNotFound,
+			Op:   "copying " + localPath,
+			Err:  fmt.Errorf("neither tar nor sh is installed in container %s", c.Container),
+		}
+	}
+	return commandError("copying "+remotePath, err, &stderr)
+}
+
+func (c Copy) noTar(p string) error {
+	return &Error{
+		Kind: KindExecutableNotFound,
+		Op:   "copying " + p,
+		Err:  fmt.Errorf("tar is not installed in container %s, only single files can be copied without it", c.Container),
+	}
+}
+
+// commandError adds what a command printed to stderr to its error.
+func commandError(op string, err error, stderr *bytes.Buffer) error {
+	if err == nil {
+		return nil
+	}
+	if message := strings.TrimSpace(stderr.String()); message != "" {
+		return &Error{Kind: KindUnknown, Op: op, Err: errors.New(message)}
+	}
+	return wrapError(op, err)
+}
+
+func (c Copy) reader(r io.Reader, total int64) io.Reader {
+	return &progressReader{Reader: r, progress: &progress{total: total, report: c.Progress}}
+}
+
+func (c Copy) writer(w io.Writer, total int64) io.Writer {
+	return &progressWriter{Writer: w, progress: &progress{total: total, report: c.Progress}}
+}
+
+// progress counts the bytes copied and reports them.
+type progress struct {
+	copied atomic.Int64
+	total  int64
+	report func(copied int64, total int64)
+}
+
+func (p *progress) add(n int) {
+	copied := p.copied.Add(int64(n))
+	if p.report != nil && n > 0 {
+		p.report(copied, p.total)
+	}
+}
+
+type progressReader struct {
+	io.Reader
+	*progress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	r.add(n)
+	return n, err
+}
+
+type progressWriter struct {
+	io.Writer
+	*progress
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	w.add(n)
+	return n, err
+}
+
+func localSize(root string) (int64, error) {
+	var size int64
+	err := filepath.Walk(root, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// writeTar writes root to w as a tar archive whose entries are named below
+// base, reporting the file contents written to progress.
+func writeTar(w io.Writer, root string, base string, progress *progress) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(base, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, &progressReader{Reader: f, progress: progress})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// untar extracts the entries of the archive below base to dest, reporting
+// the file contents written to progress. The directory dest is in must
+// exist; directories below it that the archive leaves out are created.
+// Entries and symlinks that would end up outside of dest are skipped, and
+// nothing is written through a symlink, since one the archive created
+// earlier may point anywhere.
+func untar(r io.Reader, base string, dest string, progress *progress) error {
+	if _, err := os.Stat(filepath.Dir(dest)); err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(header.Name)
+		if name != base && !strings.HasPrefix(name, base+"/") {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(strings.TrimPrefix(name, base)))
+		if !within(dest, target) || linked(dest, target) {
+			continue
+		}
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0o700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, &progressReader{Reader: tr, progress: progress})
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			resolved := filepath.Join(filepath.Dir(target), header.Linkname)
+			if filepath.IsAbs(header.Linkname) || !within(dest, resolved) {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// linked tells whether p, or a directory between dest and p, is a symlink.
+// dest itself only counts for the entries below it.
+func linked(dest string, p string) bool {
+	rel, err := filepath.Rel(dest, p)
+	if err != nil {
+		return true
+	}
+	if rel == "." {
+		return false
+	}
+	dir := dest
+	for _, part := range append([]string{"."}, strings.Split(rel, string(filepath.Separator))...) {
+		dir = filepath.Join(dir, part)
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func within(root string, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,144 @@
+package k8s
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type tarEntry struct {
+	name string
+	link string
+	body string
+	dir  bool
+}
+
+func archive(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		switch {
+		case e.dir:
+			header.Typeflag, header.Mode = tar.TypeDir, 0o755
+		case e.link != "":
+			header.Typeflag, header.Linkname = tar.TypeSymlink, e.link
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// files lists the files and symlinks below root, relative to it.
+func files(t *testing.T, root string) []string {
+	t.Helper()
+	var out []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		out = append(out, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestUntar(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		want    []string
+	}{
+		{
+			name:    "directory",
+			entries: []tarEntry{{name: "app", dir: true}, {name: "app/a.txt", body: "a"}, {name: "app/sub", dir: true}, {name: "app/sub/b.txt", body: "b"}},
+			want:    []string{"dest/a.txt", "dest/sub/b.txt"},
+		},
+		{
+			name:    "directories left out of the archive",
+			entries: []tarEntry{{name: "app/deep/er/c.txt", body: "c"}},
+			want:    []string{"dest/deep/er/c.txt"},
+		},
+		{
+			name:    "parent paths",
+			entries: []tarEntry{{name: "app/a.txt", body: "a"}, {name: "app/../../up.txt", body: "x"}, {name: "app/sub/../../../up.txt", body: "x"}},
+			want:    []string{"dest/a.txt"},
+		},
+		{
+			name:    "absolute paths",
+			entries: []tarEntry{{name: "/app/abs.txt", body: "x"}, {name: "/etc/passwd", body: "x"}},
+		},
+		{
+			name:    "other entries",
+			entries: []tarEntry{{name: "other/a.txt", body: "x"}, {name: "apple.txt", body: "x"}},
+		},
+		{
+			name:    "symlinks out of dest",
+			entries: []tarEntry{{name: "app/up", link: "../../outside"}, {name: "app/abs", link: "/etc"}, {name: "app/ok", link: "sub/a.txt"}},
+			want:    []string{"dest/ok"},
+		},
+		{
+			name: "writing through a symlink",
+			entries: []tarEntry{
+				{name: "app/self", link: "."},
+				{name: "app/up", link: "self/.."},
+				{name: "app/up/pwned.txt", body: "x"},
+				{name: "app/self/a.txt", body: "x"},
+			},
+			want: []string{"dest/self", "dest/up"},
+		},
+		{
+			name:    "file over a symlink",
+			entries: []tarEntry{{name: "app/a.txt", body: "a"}, {name: "app/link", link: "a.txt"}, {name: "app/link", body: "x"}},
+			want:    []string{"dest/a.txt", "dest/link"},
+		},
+		{
+			name:    "dest as a symlink",
+			entries: []tarEntry{{name: "app", link: "."}, {name: "app/x.txt", body: "x"}},
+			want:    []string{"dest/x.txt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if err := untar(archive(t, tt.entries...), "app", filepath.Join(root, "dest"), &progress{total: -1}); err != nil {
+				t.Fatal(err)
+			}
+			if got := files(t, root); !slices.Equal(got, tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+			if data, err := os.ReadFile(filepath.Join(root, "dest", "a.txt")); err == nil && string(data) != "a" {
+				t.Errorf("a.txt was overwritten with %q", data)
+			}
+		})
+	}
+}
+
+func TestUntarNeedsParentOfDest(t *testing.T) {
+	root := t.TempDir()
+	dest := filepath.Join(root, "missing", "dest")
+	err := untar(archive(t, tarEntry{name: "app/a.txt", body: "a"}), "app", dest, &progress{total: -1})
+	if !os.IsNotExist(err) {
+		t.Errorf("err = %v, want the parent of dest not to exist", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "missing")); !os.IsNotExist(err) {
+		t.Error("the parent of dest was created")
+	}
+}
@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// DebugImage is the image of the ephemeral containers created by
+// CreateDebugContainer.
+var DebugImage = "busybox"
+
+// debugShell is the process of the debug containers, which sessions attach
+// to.
+const debugShell = "sh"
+
+// debugTimeout bounds how long CreateDebugContainer waits for the ephemeral
+// container to start, which includes pulling its image.
+const debugTimeout = 2 * time.Minute
+
+// CreateDebugContainer adds an ephemeral container running a shell to the
+// pod and waits until it is running. If target is set, the container shares
+// the process namespace of that container. It returns the name of the new
+// container.
+func CreateDebugContainer(clientset kubernetes.Interface, namespace string, podName string, target string, image string) (string, error) {
+	pod, err := GetPod(clientset, namespace, podName)
+	if err != nil {
+		return "", err
+	}
+
+	name := "debugger-" + utilrand.String(5)
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    image,
+			Command:                  []string{debugShell},
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+			Stdin:                    true,
+			TTY:                      true,
+		},
+		TargetContainerName: target,
+	})
+	_, err = clientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(context.TODO(), podName, pod, metav1.UpdateOptions{})
+	if err != nil {
+		return "", wrapError("creating debug container", err)
+	}
+
+	var lastErr error
+	err = wait.PollUntilContextTimeout(context.TODO(), time.Second, debugTimeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := GetPod(clientset, namespace, podName)
+		if err != nil {
+			return false, err
+		}
+		lastErr = CheckExecutable(pod, name)
+		for _, s := range pod.Status.EphemeralContainerStatuses {
+			if s.Name == name && s.State.Terminated != nil {
+				return false, lastErr
+			}
+		}
+		return lastErr == nil, nil
+	})
+	if wait.Interrupted(err) {
+		return "", &Error{Kind: KindContainerNotRunning, Op: "waiting for debug container", Err: fmt.Errorf("timed out after %s: %w", debugTimeout, lastErr)}
+	}
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// AttachDebugContainer connects the local terminal to the shell of a debug
+// container. The container ends with the shell.
+func AttachDebugContainer(clientset kubernetes.Interface, config *rest.Config, namespace string, pod string, container string) error {
+	start := time.Now()
+	err := attachContainer(clientset, config, namespace, pod, container)
+	auditSession(AuditEntry{Namespace: namespace, Pod: pod, Container: container, Command: []string{debugShell}, Shell: true}, start, err)
+	return err
+}
@@ -0,0 +1,104 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+type ErrorKind int
+
+const (
+	KindUnknown ErrorKind = iota
+	KindForbidden
+	KindNotFound
+	KindUnreachable
+	KindAuthExpired
+	KindContainerNotRunning
+	KindExecutableNotFound
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindForbidden:
+		return "forbidden"
+	case KindNotFound:
+		return "not found"
+	case KindUnreachable:
+		return "cluster unreachable"
+	case KindAuthExpired:
+		return "authentication expired"
+	case KindContainerNotRunning:
+		return "container not running"
+	case KindExecutableNotFound:
+		return "executable not found"
+	default:
+		return "error"
+	}
+}
+
+var (
+	ErrForbidden   = &Error{Kind: KindForbidden}
+	ErrNotFound    = &Error{Kind: KindNotFound}
+	ErrUnreachable = &Error{Kind: KindUnreachable}
+	ErrAuthExpired = &Error{Kind: KindAuthExpired}
+
+	ErrContainerNotRunning = &Error{Kind: KindContainerNotRunning}
+	ErrExecutableNotFound  = &Error{Kind: KindExecutableNotFound}
+)
+
+// Error wraps failures of the functions in this package with the operation
+// that failed and a coarse classification, so callers can use errors.Is
+// against ErrForbidden, ErrNotFound, ErrUnreachable and ErrAuthExpired.
+type Error struct {
+	Kind ErrorKind
+	Op   string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.String()
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Op, e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Op == "" && t.Err == nil && t.Kind == e.Kind
+}
+
+func wrapError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Kind: classify(err), Op: op, Err: err}
+}
+
+func classify(err error) ErrorKind {
+	switch {
+	case apierrors.IsForbidden(err):
+		return KindForbidden
+	case apierrors.IsNotFound(err):
+		return KindNotFound
+	case apierrors.IsUnauthorized(err):
+		return KindAuthExpired
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), apierrors.IsServiceUnavailable(err):
+		return KindUnreachable
+	}
+	var opErr *net.OpError
+	var urlErr *url.Error
+	if errors.As(err, &opErr) || errors.As(err, &urlErr) {
+		return KindUnreachable
+	}
+	return KindUnknown
+}
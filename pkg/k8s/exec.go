@@ -0,0 +1,181 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// RunOptions describe a command run without a TTY. Stdin may be nil, in
+// which case no stdin stream is opened. If Context is set, the connection
+// is closed once it is done.
+type RunOptions struct {
+	Command []string
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Context context.Context
+}
+
+func execConfig(config *rest.Config) *rest.Config {
+	restconfig := rest.CopyConfig(config)
+	restconfig.GroupVersion = &schema.GroupVersion{}
+	restconfig.NegotiatedSerializer = runtime.NewSimpleNegotiatedSerializer(runtime.SerializerInfo{})
+	return restconfig
+}
+
+func execRequest(clientset kubernetes.Interface, namespace string, pod string, options *corev1.PodExecOptions) *rest.Request {
+	return clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pod).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(options, scheme.ParameterCodec)
+}
+
+func attachRequest(clientset kubernetes.Interface, namespace string, pod string, options *corev1.PodAttachOptions) *rest.Request {
+	return clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pod).
+		Namespace(namespace).
+		SubResource("attach").
+		VersionedParams(options, scheme.ParameterCodec)
+}
+
+func runCommand(clientset kubernetes.Interface, config *rest.Config, namespace string, podName string, container string, o RunOptions) error {
+	pod, err := GetPod(clientset, namespace, podName)
+	if err != nil {
+		return err
+	}
+	if err := CheckExecutable(pod, container); err != nil {
+		return err
+	}
+	req := execRequest(clientset, namespace, podName, &corev1.PodExecOptions{
+		Container: container,
+		Command:   o.Command,
+		Stdin:     o.Stdin != nil,
+		Stdout:    o.Stdout != nil,
+		Stderr:    o.Stderr != nil,
+	})
+	var stdout *headWriter
+	var out io.Writer
+	if o.Stdout != nil {
+		stdout = &headWriter{w: o.Stdout}
+		out = stdout
+	}
+	ctx := o.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	err = streamExec(ctx, req.URL(), execConfig(config), remotecommand.StreamOptions{Stdin: o.Stdin, Stdout: out, Stderr: o.Stderr})
+	return execError(err, stdout)
+}
+
+// streamExec does what exec.DefaultRemoteExecutor does, but with a context.
+func streamExec(ctx context.Context, url *url.URL, config *rest.Config, options remotecommand.StreamOptions) error {
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", url)
+	if err != nil {
+		return err
+	}
+	if cmdutil.RemoteCommandWebsockets.IsEnabled() {
+		websocketExecutor, err := remotecommand.NewWebSocketExecutor(config, "GET", url.String())
+		if err != nil {
+			return err
+		}
+		executor, err = remotecommand.NewFallbackExecutor(websocketExecutor, executor, httpstream.IsUpgradeFailure)
+		if err != nil {
+			return err
+		}
+	}
+	return executor.StreamWithContext(ctx, options)
+}
+
+// ExitCode returns the exit code of the remote command that caused err, or
+// -1 if err did not come from a command exiting.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus()
+	}
+	return -1
+}
+
+// headWriter remembers the first bytes written through it, so that the
+// message of a failed exec can be told apart from the output of a session
+// that actually started.
+type headWriter struct {
+	w       io.Writer
+	mu      sync.Mutex
+	head    strings.Builder
+	written int
+}
+
+const headSize = 512
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	if room := headSize - h.head.Len(); room > 0 {
+		h.head.Write(p[:min(room, len(p))])
+	}
+	h.written += len(p)
+	h.mu.Unlock()
+	return h.w.Write(p)
+}
+
+// onlyLine returns the output if it is a single line, which is all the
+// runtime writes when it fails to start the command. A session that
+// started has written more, e.g. its prompt, before it failed.
+func (h *headWriter) onlyLine() (string, bool) {
+	if h == nil {
+		return "", false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	line := strings.TrimSpace(h.head.String())
+	if h.written > headSize || strings.Contains(line, "\n") {
+		return "", false
+	}
+	return line, true
+}
+
+var notFoundMessages = []string{
+	"executable file not found",
+	"no such file or directory",
+}
+
+// execError wraps the error of an exec call. Errors caused by the command
+// not existing in the container are classified as ErrExecutableNotFound.
+// The runtime reports those either as the error itself or, in TTY mode, as
+// the only output followed by exit code 126 or 127. Sessions that wrote
+// anything else ran, and their exit code is that of their last command.
+func execError(err error, out *headWriter) error {
+	if err == nil {
+		return nil
+	}
+	code := ExitCode(err)
+	line, only := out.onlyLine()
+	for _, m := range notFoundMessages {
+		if strings.Contains(err.Error(), m) ||
+			((code == 126 || code == 127) && only && strings.Contains(line, m) && strings.Contains(line, "exec")) {
+			return &Error{Kind: KindExecutableNotFound, Op: "executing command", Err: err}
+		}
+	}
+	return wrapError("executing command", err)
+}
@@ -0,0 +1,39 @@
+package k8s
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func TestExecError(t *testing.T) {
+	runtimeMessage := "OCI runtime exec failed: exec failed: unable to start container process: exec: \"bash\": executable file not found in $PATH: unknown\r\n"
+	tests := []struct {
+		name     string
+		err      error
+		output   []string
+		notFound bool
+	}{
+		{name: "error names the missing executable", err: errors.New(`exec: "bash": executable file not found in $PATH`), notFound: true},
+		{name: "runtime message in tty mode", err: utilexec.CodeExitError{Err: errors.New("exit"), Code: 127}, output: []string{runtimeMessage}, notFound: true},
+		{name: "runtime message in pieces", err: utilexec.CodeExitError{Err: errors.New("exit"), Code: 126}, output: []string{runtimeMessage[:30], runtimeMessage[30:]}, notFound: true},
+		{name: "last command of a session", err: utilexec.CodeExitError{Err: errors.New("exit"), Code: 127}, output: []string{"/ # ", "exec ./run\r\n", "exec ./run: no such file or directory\r\n"}},
+		{name: "message after other output", err: utilexec.CodeExitError{Err: errors.New("exit"), Code: 127}, output: []string{"welcome\r\n", runtimeMessage}},
+		{name: "other exit code", err: utilexec.CodeExitError{Err: errors.New("exit"), Code: 1}, output: []string{runtimeMessage}},
+		{name: "connection error", err: errors.New("connection reset by peer")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &headWriter{w: io.Discard}
+			for _, o := range tt.output {
+				_, _ = out.Write([]byte(o))
+			}
+			err := execError(tt.err, out)
+			if got := errors.Is(err, ErrExecutableNotFound); got != tt.notFound {
+				t.Errorf("not found = %v, want %v: %v", got, tt.notFound, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/samox73/ksh/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type ExecCall struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+}
+
+// Client serves namespaces, pods and containers from an in-memory clientset
+// and records exec calls instead of opening a connection.
+type Client struct {
+	k8s.Client
+	Clientset *fake.Clientset
+	Execs     []ExecCall
+	// ExecFunc, if set, decides the outcome of every exec call.
+	ExecFunc func(call ExecCall) error
+	Runs     []ExecCall
+	// Attaches records the containers a terminal was attached to.
+	Attaches []ExecCall
+	// RunFunc, if set, decides the outcome of every non-interactive command.
+	RunFunc func(call ExecCall, options k8s.RunOptions) error
+	// NodeShells records the nodes a shell was opened on.
+	NodeShells []string
+	// mu guards the recorded calls, since commands may run concurrently.
+	mu sync.Mutex
+}
+
+func NewClient(objects ...runtime.Object) *Client {
+	clientset := fake.NewSimpleClientset(objects...)
+	return &Client{Client: k8s.NewClient(clientset, nil), Clientset: clientset}
+}
+
+func (c *Client) Exec(namespace string, pod string, container string, command []string) error {
+	call := ExecCall{Namespace: namespace, Pod: pod, Container: container, Command: command}
+	c.mu.Lock()
+	c.Execs = append(c.Execs, call)
+	c.mu.Unlock()
+	if c.ExecFunc != nil {
+		return c.ExecFunc(call)
+	}
+	return nil
+}
+
+func (c *Client) Run(namespace string, pod string, container string, options k8s.RunOptions) error {
+	call := ExecCall{Namespace: namespace, Pod: pod, Container: container, Command: options.Command}
+	c.mu.Lock()
+	c.Runs = append(c.Runs, call)
+	c.mu.Unlock()
+	if c.RunFunc != nil {
+		return c.RunFunc(call, options)
+	}
+	return nil
+}
+
+// Debug adds an ephemeral container to the pod and marks it as running
+// right away, since there is no kubelet to start it.
+func (c *Client) Debug(namespace string, pod string, target string, image string) (string, error) {
+	p, err := c.Clientset.CoreV1().Pods(namespace).Get(context.TODO(), pod, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("debugger-%d", len(p.Spec.EphemeralContainers))
+	p.Spec.EphemeralContainers = append(p.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: name, Image: image},
+		TargetContainerName:      target,
+	})
+	p.Status.EphemeralContainerStatuses = append(p.Status.EphemeralContainerStatuses, corev1.ContainerStatus{
+		Name:  name,
+		Image: image,
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	})
+	if _, err := c.Clientset.CoreV1().Pods(namespace).Update(context.TODO(), p, metav1.UpdateOptions{}); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Attach records the container instead of attaching to it.
+func (c *Client) Attach(namespace string, pod string, container string) error {
+	c.mu.Lock()
+	c.Attaches = append(c.Attaches, ExecCall{Namespace: namespace, Pod: pod, Container: container})
+	c.mu.Unlock()
+	return nil
+}
+
+// PortForward fails, since there is no pod to connect to.
+func (c *Client) PortForward(namespace string, pod string, localPort int, remotePort int) (*k8s.Forward, error) {
+	return nil, errors.New("port forwarding is not supported by the fake client")
+}
+
+// NodeShell records the node instead of starting a pod on it.
+func (c *Client) NodeShell(node string) error {
+	c.mu.Lock()
+	c.NodeShells = append(c.NodeShells, node)
+	c.mu.Unlock()
+	return nil
+}
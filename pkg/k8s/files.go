@@ -0,0 +1,149 @@
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileEntry is a file in a directory of a container. Everything but the
+// name and whether it is a directory is only known if the container has
+// stat.
+type FileEntry struct {
+	Name    string
+	Dir     bool
+	Link    bool
+	Size    int64
+	Mode    string
+	ModTime time.Time
+}
+
+// statBatch is how many files are passed to a single stat call.
+const statBatch = 1000
+
+// ListDir lists the files in a directory of a container with ls and stat,
+// sorted with the directories first.
+func ListDir(client Client, namespace string, pod string, container string, dir string) ([]FileEntry, error) {
+	var stdout, stderr bytes.Buffer
+	err := client.Run(namespace, pod, container, RunOptions{
+		Command: []string{"ls", "-1Ap", "--", dir},
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	})
+	if err != nil {
+		return nil, commandError("listing "+dir, err, &stderr)
+	}
+	var entries []FileEntry
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		name, dir := strings.CutSuffix(line, "/")
+		entries = append(entries, FileEntry{Name: name, Dir: dir})
+	}
+	for start := 0; start < len(entries); start += statBatch {
+		batch := entries[start:min(start+statBatch, len(entries))]
+		if !statEntries(client, namespace, pod, container, dir, batch) {
+			break
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Dir != entries[j].Dir {
+			return entries[i].Dir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// statEntries fills in the details of the entries. It returns false if the
+// container has no usable stat.
+func statEntries(client Client, namespace string, pod string, container string, dir string, entries []FileEntry) bool {
+	command := []string{"stat", "-c", "%F\t%s\t%A\t%Y\t%n", "--"}
+	byPath := map[string]*FileEntry{}
+	for i := range entries {
+		p := path.Join(dir, entries[i].Name)
+		command = append(command, p)
+		byPath[p] = &entries[i]
+	}
+	var stdout bytes.Buffer
+	err := client.Run(namespace, pod, container, RunOptions{Command: command, Stdout: &stdout, Stderr: &bytes.Buffer{}})
+	// stat fails if some of the files vanished in the meantime, but still
+	// prints the others.
+	if errors.Is(err, ErrExecutableNotFound) || (err != nil && stdout.Len() == 0) {
+		return false
+	}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		fields := strings.SplitN(line, "\t", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		e, ok := byPath[fields[4]]
+		if !ok {
+			continue
+		}
+		e.Link = fields[0] == "symbolic link"
+		e.Size, _ = strconv.ParseInt(fields[1], 10, 64)
+		e.Mode = fields[2]
+		if seconds, err := strconv.ParseInt(fields[3], 10, 64); err == nil {
+			e.ModTime = time.Unix(seconds, 0)
+		}
+	}
+	return true
+}
+
+// IsDir tells whether p is a directory, or a link to one, in a container.
+func IsDir(client Client, namespace string, pod string, container string, p string) bool {
+	err := client.Run(namespace, pod, container, RunOptions{
+		Command: []string{"test", "-d", p},
+		Stdout:  io.Discard,
+	})
+	return err == nil
+}
+
+// limitWriter keeps the first limit bytes written to it and drops the rest.
+type limitWriter struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.buf.Len(); len(p) > room {
+		w.buf.Write(p[:room])
+		w.truncated = true
+		return len(p), nil
+	}
+	return w.buf.Write(p)
+}
+
+// ReadFile reads up to limit bytes of a file in a container. truncated tells
+// whether the file is longer than that. It uses head, so that only the start
+// of large files is transferred, or cat for containers without head.
+func ReadFile(client Client, namespace string, pod string, container string, file string, limit int) (content []byte, truncated bool, err error) {
+	out := &limitWriter{limit: limit}
+	var stderr bytes.Buffer
+	err = client.Run(namespace, pod, container, RunOptions{
+		Command: []string{"head", "-c", strconv.Itoa(limit + 1), "--", file},
+		Stdout:  out,
+		Stderr:  &stderr,
+	})
+	if errors.Is(err, ErrExecutableNotFound) {
+		out = &limitWriter{limit: limit}
+		stderr.Reset()
+		err = client.Run(namespace, pod, container, RunOptions{
+			Command: []string{"cat", "--", file},
+			Stdout:  out,
+			Stderr:  &stderr,
+		})
+	}
+	if err != nil {
+		return nil, false, commandError("reading "+file, err, &stderr)
+	}
+	return out.buf.Bytes(), out.truncated, nil
+}
@@ -0,0 +1,274 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LaunchFile keeps the favourite and recently used containers.
+var LaunchFile = dataPath("state.json")
+
+// maxRecents is how many recently used containers are kept.
+const maxRecents = 20
+
+// Launch is a container that can be opened again after its pod was
+// replaced. The pod is found through the workload that owns it, written as
+// kind/name, through a label selector, or else by the prefix of its name.
+type Launch struct {
+	Context   string    `json:"context"`
+	Namespace string    `json:"namespace"`
+	Workload  string    `json:"workload,omitempty"`
+	Selector  string    `json:"selector,omitempty"`
+	PodPrefix string    `json:"podPrefix,omitempty"`
+	Container string    `json:"container"`
+	LastUsed  time.Time `json:"lastUsed"`
+}
+
+// Match describes how the pod of the launch is found.
+func (l Launch) Match() string {
+	switch {
+	case l.Workload != "":
+		return l.Workload
+	case l.Selector != "":
+		return l.Selector
+	default:
+		return l.PodPrefix + "*"
+	}
+}
+
+// Target names the launch within its context.
+func (l Launch) Target() string {
+	return l.Namespace + "/" + l.Match() + "/" + l.Container
+}
+
+func (l Launch) same(other Launch) bool {
+	return l.Context == other.Context && l.Target() == other.Target()
+}
+
+// NewLaunch describes the container of the pod in the current context by
+// the workload owning the pod. The owner chain is followed from a
+// ReplicaSet to its Deployment and from a Job to its CronJob. Pods whose
+// owner cannot be found again, because it is not a workload or is itself
+// controlled by something else, are matched by their full name.
+func NewLaunch(client Client, pod *corev1.Pod, container string) Launch {
+	l := Launch{Namespace: pod.Namespace, Container: container, PodPrefix: pod.Name}
+	l.Context, _ = CurrentContext()
+	controller := metav1.GetControllerOf(pod)
+	if controller == nil || !isWorkloadKind(controller.Kind) {
+		return l
+	}
+	owner := controller
+	if parentKind, ok := parentKinds[controller.Kind]; ok {
+		parent, err := client.Controller(pod.Namespace, *controller)
+		if err != nil {
+			return l
+		}
+		if parent != nil {
+			if parent.Kind != parentKind {
+				return l
+			}
+			owner = parent
+		}
+	}
+	l.Workload, l.PodPrefix = owner.Kind+"/"+owner.Name, ""
+	return l
+}
+
+// parentKinds maps the kinds that are usually created by another workload
+// to the kind of that workload.
+var parentKinds = map[string]string{"ReplicaSet": "Deployment", "Job": "CronJob"}
+
+func isWorkloadKind(kind string) bool {
+	for k := Deployment; k <= CronJob; k++ {
+		if k.String() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Launches are the favourite and the recently used containers, most
+// recently used first.
+type Launches struct {
+	Favourites []Launch `json:"favourites"`
+	Recents    []Launch `json:"recents"`
+}
+
+// LoadLaunches reads LaunchFile. A missing file is no error.
+func LoadLaunches() (Launches, error) {
+	var launches Launches
+	data, err := os.ReadFile(LaunchFile)
+	if os.IsNotExist(err) {
+		return launches, nil
+	}
+	if err != nil {
+		return launches, err
+	}
+	if err := json.Unmarshal(data, &launches); err != nil {
+		return launches, fmt.Errorf("reading %s: %w", LaunchFile, err)
+	}
+	return launches, nil
+}
+
+// Save writes LaunchFile, replacing it at once so that concurrent ksh
+// processes never read half a file.
+func (s Launches) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(LaunchFile), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(LaunchFile), ".state-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), LaunchFile)
+}
+
+// IsFavourite tells whether the launch is pinned.
+func (s Launches) IsFavourite(l Launch) bool {
+	return indexOf(s.Favourites, l) >= 0
+}
+
+// Pin makes the launch a favourite, or turns the favourite it replaces
+// into l.
+func (s *Launches) Pin(l Launch) {
+	if i := indexOf(s.Favourites, l); i >= 0 {
+		s.Favourites[i] = l
+		return
+	}
+	s.Favourites = append(s.Favourites, l)
+}
+
+// Unpin drops the launch from the favourites.
+func (s *Launches) Unpin(l Launch) {
+	s.Favourites = without(s.Favourites, l)
+}
+
+// Remove drops the launch from the favourites and the recents.
+func (s *Launches) Remove(l Launch) {
+	s.Favourites = without(s.Favourites, l)
+	s.Recents = without(s.Recents, l)
+}
+
+// Use moves the launch to the front of the recents and updates the
+// favourite it matches.
+func (s *Launches) Use(l Launch) {
+	l.LastUsed = time.Now()
+	if i := indexOf(s.Favourites, l); i >= 0 {
+		s.Favourites[i].LastUsed = l.LastUsed
+	}
+	s.Recents = append([]Launch{l}, without(s.Recents, l)...)
+	if len(s.Recents) > maxRecents {
+		s.Recents = s.Recents[:maxRecents]
+	}
+}
+
+func indexOf(launches []Launch, l Launch) int {
+	for i, other := range launches {
+		if other.same(l) {
+			return i
+		}
+	}
+	return -1
+}
+
+func without(launches []Launch, l Launch) []Launch {
+	out := launches[:0:0]
+	for _, other := range launches {
+		if !other.same(l) {
+			out = append(out, other)
+		}
+	}
+	return out
+}
+
+// rememberLaunch adds the container to the recents. It is best effort, a
+// state file that cannot be read or written is left alone.
+func rememberLaunch(client Client, namespace string, podName string, container string) {
+	pod, err := client.Pod(namespace, podName)
+	if err != nil {
+		return
+	}
+	launches, err := LoadLaunches()
+	if err != nil {
+		return
+	}
+	launches.Use(NewLaunch(client, pod, container))
+	_ = launches.Save()
+}
+
+// ResolveLaunch finds the pod to open the launch in, among the pods whose
+// container is running: ready pods first, then the newest. The client must
+// belong to the context of the launch.
+func ResolveLaunch(client Client, l Launch) (string, error) {
+	var selector PodSelector
+	switch {
+	case l.Workload != "":
+		kind, name, _ := strings.Cut(l.Workload, "/")
+		workloads, err := client.Workloads(l.Namespace)
+		if err != nil {
+			return "", err
+		}
+		found := false
+		for _, w := range workloads {
+			if w.Kind.String() == kind && w.Name == name {
+				selector, found = w.Pods(), true
+				break
+			}
+		}
+		if !found {
+			return "", &Error{Kind: KindNotFound, Op: "resolving pod", Err: fmt.Errorf("%s not found in namespace %s", l.Workload, l.Namespace)}
+		}
+	case l.Selector != "":
+		var err error
+		if selector, err = ParseSelector(l.Selector); err != nil {
+			return "", err
+		}
+	}
+	list, err := client.PodPage(l.Namespace, selector, 0, "")
+	if err != nil {
+		return "", err
+	}
+	var pods []corev1.Pod
+	controller := cachedControllers(client.Controller)
+	for _, pod := range list.Items {
+		if selector.matches(&pod, controller) && strings.HasPrefix(pod.Name, l.PodPrefix) &&
+			pod.DeletionTimestamp == nil && CheckExecutable(&pod, l.Container) == nil {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		return "", &Error{Kind: KindContainerNotRunning, Op: "resolving pod", Err: fmt.Errorf("no pod of %s in namespace %s has a running container %s", l.Match(), l.Namespace, l.Container)}
+	}
+	sort.SliceStable(pods, func(i, j int) bool {
+		if ready := podReady(pods[i]); ready != podReady(pods[j]) {
+			return ready
+		}
+		return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp)
+	})
+	return pods[0].Name, nil
+}
+
+func podReady(pod corev1.Pod) bool {
+	ready, total := PodReadiness(pod)
+	return total > 0 && ready == total
+}
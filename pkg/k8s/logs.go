@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"context"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LogOptions selects the logs of a container that are streamed.
+type LogOptions struct {
+	Container  string
+	Follow     bool
+	Previous   bool
+	Timestamps bool
+	// Since limits the logs to the ones newer than the duration, if set.
+	Since time.Duration
+	// TailLines limits the logs to the last lines, if set.
+	TailLines int64
+}
+
+// StreamLogs opens the logs of a container. The stream ends when the
+// container stops or, without Follow, once the existing logs were read.
+func StreamLogs(clientset kubernetes.Interface, namespaceName string, podName string, options LogOptions) (io.ReadCloser, error) {
+	logOptions := &corev1.PodLogOptions{
+		Container:  options.Container,
+		Follow:     options.Follow,
+		Previous:   options.Previous,
+		Timestamps: options.Timestamps,
+	}
+	if options.Since > 0 {
+		seconds := int64(options.Since.Seconds())
+		logOptions.SinceSeconds = &seconds
+	}
+	if options.TailLines > 0 {
+		tail := options.TailLines
+		logOptions.TailLines = &tail
+	}
+	stream, err := clientset.CoreV1().Pods(namespaceName).GetLogs(podName, logOptions).Stream(context.TODO())
+	if err != nil {
+		return nil, wrapError("streaming logs", err)
+	}
+	return stream, nil
+}
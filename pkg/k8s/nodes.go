@@ -0,0 +1,168 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubectl/pkg/util/interrupt"
+)
+
+const nodeRolePrefix = "node-role.kubernetes.io/"
+
+// NodeRoles returns the roles of a node from its node-role labels, sorted.
+func NodeRoles(node corev1.Node) []string {
+	var roles []string
+	for label := range node.Labels {
+		if role, ok := strings.CutPrefix(label, nodeRolePrefix); ok && role != "" {
+			roles = append(roles, role)
+		}
+	}
+	if role := node.Labels["kubernetes.io/role"]; role != "" && len(roles) == 0 {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// NodeConditions describes a node like kubectl: Ready or NotReady, followed
+// by the pressure conditions that are set and whether it is cordoned.
+func NodeConditions(node corev1.Node) []string {
+	status := []string{"Unknown"}
+	for _, c := range node.Status.Conditions {
+		switch {
+		case c.Type == corev1.NodeReady && c.Status == corev1.ConditionTrue:
+			status[0] = "Ready"
+		case c.Type == corev1.NodeReady:
+			status[0] = "NotReady"
+		case c.Status == corev1.ConditionTrue:
+			status = append(status, string(c.Type))
+		}
+	}
+	if node.Spec.Unschedulable {
+		status = append(status, "SchedulingDisabled")
+	}
+	return status
+}
+
+const (
+	nodeShellContainer = "shell"
+	// nodeShellLifetime bounds how long a node shell pod keeps running if
+	// ksh is killed before it can delete the pod.
+	nodeShellLifetime = 12 * time.Hour
+	// nodeShellScript enters the namespaces of the node's init process, or
+	// changes into its root filesystem if the image has no nsenter, and
+	// starts the node's shell there.
+	nodeShellScript = `if command -v nsenter >/dev/null 2>&1; then
+  exec nsenter --target 1 --mount --uts --ipc --net --pid -- sh -c "$0"
+fi
+exec chroot /host sh -c "$0"`
+	hostShellScript = `if command -v bash >/dev/null 2>&1; then exec bash -l; fi; exec sh -l`
+)
+
+// openNodeShell starts a privileged pod on the node that shares its process
+// and network namespaces and mounts its root filesystem, and opens a shell
+// on the node through it. The pod is deleted when the shell exits or ksh is
+// interrupted.
+func openNodeShell(clientset kubernetes.Interface, config *rest.Config, node string) error {
+	namespace, err := Namespace()
+	if err != nil {
+		return err
+	}
+	pod, err := clientset.CoreV1().Pods(namespace).Create(context.TODO(), nodeShellPod(node), metav1.CreateOptions{})
+	if err != nil {
+		return wrapError("creating node shell pod", err)
+	}
+	var once sync.Once
+	cleanup := func() {
+		once.Do(func() { deleteNodeShellPod(clientset, namespace, pod.Name) })
+	}
+	defer cleanup()
+
+	fmt.Printf("Waiting for pod %s/%s on node %s\n", namespace, pod.Name, node)
+	err = interrupt.New(nil, cleanup).Run(func() error {
+		return waitForNodeShellPod(clientset, namespace, pod.Name)
+	})
+	if err != nil {
+		return err
+	}
+	command := []string{"sh", "-c", nodeShellScript, hostShellScript}
+	start := time.Now()
+	err = openSpecificShell(clientset, config, namespace, pod.Name, nodeShellContainer, command, interrupt.New(nil, cleanup))
+	auditSession(AuditEntry{Namespace: namespace, Pod: pod.Name, Container: nodeShellContainer, Node: node, Command: command, Shell: true}, start, err)
+	return err
+}
+
+func nodeShellPod(node string) *corev1.Pod {
+	privileged := true
+	var grace int64
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "ksh-node-shell-" + utilrand.String(5),
+			Labels: map[string]string{"app.kubernetes.io/managed-by": "ksh"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:                      node,
+			HostPID:                       true,
+			HostNetwork:                   true,
+			HostIPC:                       true,
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			TerminationGracePeriodSeconds: &grace,
+			Tolerations:                   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			Containers: []corev1.Container{{
+				Name:            nodeShellContainer,
+				Image:           DebugImage,
+				Command:         []string{"sleep", fmt.Sprint(int(nodeShellLifetime.Seconds()))},
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+				VolumeMounts:    []corev1.VolumeMount{{Name: "host", MountPath: "/host"}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name:         "host",
+				VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/"}},
+			}},
+		},
+	}
+}
+
+func waitForNodeShellPod(clientset kubernetes.Interface, namespace string, name string) error {
+	var lastErr error
+	err := wait.PollUntilContextTimeout(context.TODO(), time.Second, debugTimeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := GetPod(clientset, namespace, name)
+		if err != nil {
+			return false, err
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			return false, fmt.Errorf("node shell pod %s/%s ended: %s", namespace, name, PodReason(*pod))
+		}
+		lastErr = CheckExecutable(pod, nodeShellContainer)
+		return lastErr == nil, nil
+	})
+	if wait.Interrupted(err) {
+		return &Error{Kind: KindContainerNotRunning, Op: "waiting for node shell pod", Err: fmt.Errorf("timed out after %s: %w", debugTimeout, lastErr)}
+	}
+	return err
+}
+
+// deleteNodeShellPod deletes the pod right away. Failures are printed, since
+// a leftover privileged pod needs the user's attention.
+func deleteNodeShellPod(clientset kubernetes.Interface, namespace string, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var grace int64
+	err := clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+	if err != nil && !apierrors.IsNotFound(err) {
+		fmt.Fprintf(os.Stderr, "Error deleting node shell pod %s/%s, delete it manually: %v\n", namespace, name, wrapError("deleting pod", err))
+	}
+}
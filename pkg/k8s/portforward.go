@@ -0,0 +1,173 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// Forward forwards a local port to a port of a pod in the background until
+// it is stopped or the connection to the pod is lost.
+type Forward struct {
+	Namespace  string
+	Pod        string
+	LocalPort  int
+	RemotePort int
+	Started    time.Time
+	sent       atomic.Int64
+	received   atomic.Int64
+	stop       chan struct{}
+	stopped    atomic.Bool
+	done       chan struct{}
+	err        error
+}
+
+// Sent returns the number of bytes sent to the pod.
+func (f *Forward) Sent() int64 { return f.sent.Load() }
+
+// Received returns the number of bytes received from the pod.
+func (f *Forward) Received() int64 { return f.received.Load() }
+
+// Done is closed once the forward has ended.
+func (f *Forward) Done() <-chan struct{} { return f.done }
+
+// Err returns why the forward ended, or nil if it is still running or was
+// stopped.
+func (f *Forward) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+func (f *Forward) Stop() {
+	if f.stopped.CompareAndSwap(false, true) {
+		close(f.stop)
+	}
+}
+
+func (f *Forward) String() string {
+	return fmt.Sprintf("localhost:%d -> %s/%s:%d", f.LocalPort, f.Namespace, f.Pod, f.RemotePort)
+}
+
+// StartForward forwards localPort to remotePort of the pod and returns once
+// the local port is listening. A localPort of 0 picks a free port.
+func StartForward(clientset kubernetes.Interface, config *rest.Config, namespaceName string, podName string, localPort int, remotePort int) (*Forward, error) {
+	pod, err := GetPod(clientset, namespaceName, podName)
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil, &Error{
+			Kind: KindContainerNotRunning,
+			Op:   "forwarding port",
+			Err:  fmt.Errorf("pod %s is %s, only running pods can be forwarded to", podName, PodReason(*pod)),
+		}
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, wrapError("forwarding port", err)
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespaceName).
+		Name(podName).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	f := &Forward{
+		Namespace:  namespaceName,
+		Pod:        podName,
+		RemotePort: remotePort,
+		Started:    time.Now(),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	ready := make(chan struct{})
+	ports := []string{fmt.Sprintf("%d:%d", localPort, remotePort)}
+	fw, err := portforward.NewOnAddresses(&countingDialer{dialer: dialer, forward: f}, []string{"localhost"}, ports, f.stop, ready, io.Discard, io.Discard)
+	if err != nil {
+		return nil, wrapError("forwarding port", err)
+	}
+	go func() {
+		f.err = fw.ForwardPorts()
+		if f.err != nil {
+			f.err = wrapError("forwarding port", f.err)
+		}
+		close(f.done)
+	}()
+
+	select {
+	case <-ready:
+	case <-f.done:
+		return nil, f.err
+	}
+	forwarded, err := fw.GetPorts()
+	if err == nil && len(forwarded) == 0 {
+		err = errors.New("no port is being forwarded")
+	}
+	if err != nil {
+		f.Stop()
+		return nil, wrapError("forwarding port", err)
+	}
+	f.LocalPort = int(forwarded[0].Local)
+	return f, nil
+}
+
+// countingDialer counts the bytes that go through the data streams of the
+// connections it dials.
+type countingDialer struct {
+	dialer  httpstream.Dialer
+	forward *Forward
+}
+
+func (d *countingDialer) Dial(protocols ...string) (httpstream.Connection, string, error) {
+	conn, protocol, err := d.dialer.Dial(protocols...)
+	if err != nil {
+		return nil, "", err
+	}
+	return &countingConnection{Connection: conn, forward: d.forward}, protocol, nil
+}
+
+type countingConnection struct {
+	httpstream.Connection
+	forward *Forward
+}
+
+func (c *countingConnection) CreateStream(headers http.Header) (httpstream.Stream, error) {
+	stream, err := c.Connection.CreateStream(headers)
+	if err != nil || headers.Get(corev1.StreamType) != corev1.StreamTypeData {
+		return stream, err
+	}
+	return &countingStream{Stream: stream, forward: c.forward}, nil
+}
+
+type countingStream struct {
+	httpstream.Stream
+	forward *Forward
+}
+
+func (s *countingStream) Read(p []byte) (int, error) {
+	n, err := s.Stream.Read(p)
+	s.forward.received.Add(int64(n))
+	return n, err
+}
+
+func (s *countingStream) Write(p []byte) (int, error) {
+	n, err := s.Stream.Write(p)
+	s.forward.sent.Add(int64(n))
+	return n, err
+}
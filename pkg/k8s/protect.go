@@ -0,0 +1,70 @@
+package k8s
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ContextDefaults are the settings of the contexts whose name matches
+// Pattern, as in path.Match. Namespace replaces the namespace of the
+// context, Protected asks before a shell or command is started in it.
+type ContextDefaults struct {
+	Pattern   string
+	Namespace string
+	Protected bool
+}
+
+// ContextSettings apply to the contexts they match. The first match wins.
+var ContextSettings []ContextDefaults
+
+// AssumeYes answers the question for protected contexts without asking.
+var AssumeYes bool
+
+// ErrNotConfirmed is returned by Confirm if the action was turned down.
+var ErrNotConfirmed = errors.New("not confirmed")
+
+func contextDefaults(context string) (ContextDefaults, bool) {
+	for _, d := range ContextSettings {
+		if ok, _ := path.Match(d.Pattern, context); ok {
+			return d, true
+		}
+	}
+	return ContextDefaults{}, false
+}
+
+// Protected tells whether the current context asks before a session.
+func Protected() bool {
+	context, err := CurrentContext()
+	if err != nil {
+		return false
+	}
+	d, _ := contextDefaults(context)
+	return d.Protected
+}
+
+// Confirm asks on out whether to go ahead with the action if the current
+// context is protected, and reads the answer from in. Without a terminal to
+// ask on, only AssumeYes lets the action go ahead.
+func Confirm(in io.Reader, out io.Writer, action string) error {
+	if AssumeYes || !Protected() {
+		return nil
+	}
+	context, _ := CurrentContext()
+	if f, ok := in.(*os.File); in == nil || ok && !term.IsTerminal(int(f.Fd())) {
+		return fmt.Errorf("context %s is protected, pass --yes to %s without asking", context, action)
+	}
+	fmt.Fprintf(out, "Context %s is protected. %s? [y/N] ", context, strings.ToUpper(action[:1])+action[1:])
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return ErrNotConfirmed
+}
@@ -0,0 +1,441 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Record records all interactive sessions, RecordContexts only those in
+// contexts matching one of the patterns, e.g. prod-*. The recordings are
+// asciicast v2 files in RecordDir.
+var (
+	Record         bool
+	RecordContexts []string
+	RecordDir      = dataPath("recordings")
+)
+
+// RecordingHeader is the first line of an asciicast v2 file. Session is
+// only set in recordings made by ksh.
+type RecordingHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Session   *RecordedSession  `json:"ksh,omitempty"`
+}
+
+// RecordedSession describes where a recording was made and by whom. User is
+// the local user, KubeUser the user of the context.
+type RecordedSession struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	User      string `json:"user"`
+	KubeUser  string `json:"kubeUser,omitempty"`
+}
+
+// recordingEvent is output ("o"), input ("i") or a terminal size change
+// ("r") at Time seconds into the recording.
+type recordingEvent struct {
+	Time float64
+	Kind string
+	Data string
+}
+
+func recordingEnabled(context string) bool {
+	if Record {
+		return true
+	}
+	for _, pattern := range RecordContexts {
+		if ok, _ := path.Match(pattern, context); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// recorder writes the events of a session to an asciicast file as they
+// happen, so that the recording survives ksh being killed.
+type recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	enc     *json.Encoder
+	header  RecordingHeader
+	start   time.Time
+	size    remotecommand.TerminalSize
+	partial map[string][]byte
+}
+
+// shellRecording is the recording of the OpenShell session in progress. The
+// attempts of a session at different shells share it, so that a session
+// makes one recording, of the shell that started. Only one interactive
+// session runs at a time.
+var shellRecording struct {
+	sync.Mutex
+	active bool
+	rec    *recorder
+}
+
+// beginShellRecording makes the sessions started until endShellRecording
+// share one recording.
+func beginShellRecording() {
+	shellRecording.Lock()
+	defer shellRecording.Unlock()
+	shellRecording.active, shellRecording.rec = true, nil
+}
+
+// endShellRecording closes the shared recording, or removes it if no shell
+// started.
+func endShellRecording(started bool) {
+	shellRecording.Lock()
+	defer shellRecording.Unlock()
+	if rec := shellRecording.rec; rec != nil {
+		if started {
+			_ = rec.Close()
+		} else {
+			rec.discard()
+		}
+	}
+	shellRecording.active, shellRecording.rec = false, nil
+}
+
+// sessionRecording returns the recording of a session in the container, and
+// whether it is shared by the attempts of an OpenShell session. A shared
+// recording that already exists starts over for the new attempt, and is
+// only announced once. It returns nil if the current context is not
+// recorded.
+func sessionRecording(namespace string, pod string, container string, command []string, size *remotecommand.TerminalSize) (*recorder, bool, error) {
+	shellRecording.Lock()
+	defer shellRecording.Unlock()
+	if rec := shellRecording.rec; shellRecording.active && rec != nil {
+		return rec, true, rec.restart(command, size)
+	}
+	rec, err := startRecording(namespace, pod, container, command, size)
+	if rec != nil {
+		fmt.Fprintf(os.Stderr, "Recording session to %s\n", rec.Path())
+	}
+	if shellRecording.active {
+		shellRecording.rec = rec
+	}
+	return rec, shellRecording.active, err
+}
+
+// startRecording creates the recording of a session in the container if
+// the current context is recorded, and returns nil otherwise.
+func startRecording(namespace string, pod string, container string, command []string, size *remotecommand.TerminalSize) (*recorder, error) {
+	context, err := CurrentContext()
+	if err != nil || !recordingEnabled(context) {
+		return nil, err
+	}
+	if size == nil {
+		size = &remotecommand.TerminalSize{Width: 80, Height: 24}
+	}
+	session := &RecordedSession{Context: context, Namespace: namespace, Pod: pod, Container: container, User: localUser()}
+	if config, err := rawConfig(); err == nil && config.Contexts[context] != nil {
+		session.KubeUser = config.Contexts[context].AuthInfo
+	}
+
+	start := time.Now()
+	if RecordDir == "" {
+		return nil, fmt.Errorf("recording session: no directory for recordings")
+	}
+	if err := os.MkdirAll(RecordDir, 0o700); err != nil {
+		return nil, fmt.Errorf("recording session: %w", err)
+	}
+	name := strings.Join([]string{start.Format("20060102T150405"), context, namespace, pod, container}, "_")
+	file, err := os.OpenFile(filepath.Join(RecordDir, recordingName(name)+".cast"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("recording session: %w", err)
+	}
+	r := &recorder{file: file, enc: json.NewEncoder(file), start: start, size: *size, partial: map[string][]byte{}}
+	r.enc.SetEscapeHTML(false)
+	r.header = RecordingHeader{
+		Version:   2,
+		Width:     int(size.Width),
+		Height:    int(size.Height),
+		Timestamp: start.Unix(),
+		Command:   strings.Join(command, " "),
+		Title:     fmt.Sprintf("%s %s/%s/%s", context, namespace, pod, container),
+		Env:       map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+		Session:   session,
+	}
+	if err := r.enc.Encode(r.header); err != nil {
+		r.discard()
+		return nil, fmt.Errorf("recording session: %w", err)
+	}
+	return r, nil
+}
+
+// restart drops what was recorded and begins the recording anew for
+// command, when a session tries another shell because the last one did not
+// exist.
+func (r *recorder) restart(command []string, size *remotecommand.TerminalSize) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.file.Truncate(0); err != nil {
+		return fmt.Errorf("recording session: %w", err)
+	}
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("recording session: %w", err)
+	}
+	r.start = time.Now()
+	if size != nil {
+		r.size = *size
+	}
+	r.partial = map[string][]byte{}
+	r.header.Width, r.header.Height = int(r.size.Width), int(r.size.Height)
+	r.header.Timestamp = r.start.Unix()
+	r.header.Command = strings.Join(command, " ")
+	if err := r.enc.Encode(r.header); err != nil {
+		return fmt.Errorf("recording session: %w", err)
+	}
+	return nil
+}
+
+// discard removes the recording of a session that never started.
+func (r *recorder) discard() {
+	_ = r.file.Close()
+	_ = os.Remove(r.file.Name())
+}
+
+// recordingName replaces characters that do not belong in file names, such
+// as the slashes and colons of EKS context names.
+func recordingName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || r < utf8.RuneSelf && (r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+func (r *recorder) Path() string {
+	return r.file.Name()
+}
+
+func (r *recorder) Close() error {
+	return r.file.Close()
+}
+
+// record writes an event. Multi-byte characters split across writes are
+// held back until they are complete, since events are JSON strings.
+func (r *recorder) record(kind string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := append(r.partial[kind], p...)
+	n := completeRunes(data)
+	r.partial[kind] = append([]byte(nil), data[n:]...)
+	if n > 0 {
+		r.event(kind, string(data[:n]))
+	}
+}
+
+func (r *recorder) resize(size remotecommand.TerminalSize) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if size != r.size {
+		r.size = size
+		r.event("r", fmt.Sprintf("%dx%d", size.Width, size.Height))
+	}
+}
+
+// event writes an event while holding mu. Errors are ignored, a failing
+// recording does not end the session.
+func (r *recorder) event(kind string, data string) {
+	elapsed := math.Round(time.Since(r.start).Seconds()*1e6) / 1e6
+	_ = r.enc.Encode([]any{elapsed, kind, data})
+}
+
+// completeRunes returns the length of b without an incomplete character at
+// its end.
+func completeRunes(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+type recordingReader struct {
+	r  *recorder
+	in io.Reader
+}
+
+func (rr recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.in.Read(p)
+	if n > 0 {
+		rr.r.record("i", p[:n])
+	}
+	return n, err
+}
+
+type recordingWriter struct {
+	r   *recorder
+	out io.Writer
+}
+
+func (rw recordingWriter) Write(p []byte) (int, error) {
+	n, err := rw.out.Write(p)
+	if n > 0 {
+		rw.r.record("o", p[:n])
+	}
+	return n, err
+}
+
+type recordingSizeQueue struct {
+	r     *recorder
+	queue remotecommand.TerminalSizeQueue
+}
+
+func (q recordingSizeQueue) Next() *remotecommand.TerminalSize {
+	size := q.queue.Next()
+	if size != nil {
+		q.r.resize(*size)
+	}
+	return size
+}
+
+// RecordingInfo is a recording in RecordDir.
+type RecordingInfo struct {
+	Path   string
+	Header RecordingHeader
+}
+
+// ListRecordings lists the recordings in RecordDir, oldest first. Files
+// that are not asciicast v2 recordings are left out.
+func ListRecordings() ([]RecordingInfo, error) {
+	entries, err := os.ReadDir(RecordDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recordings []RecordingInfo
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".cast" {
+			continue
+		}
+		p := filepath.Join(RecordDir, e.Name())
+		file, err := os.Open(p)
+		if err != nil {
+			continue
+		}
+		header, err := readRecordingHeader(bufio.NewReader(file))
+		_ = file.Close()
+		if err == nil {
+			recordings = append(recordings, RecordingInfo{Path: p, Header: header})
+		}
+	}
+	sort.SliceStable(recordings, func(i, j int) bool {
+		return recordings[i].Header.Timestamp < recordings[j].Header.Timestamp
+	})
+	return recordings, nil
+}
+
+func readRecordingHeader(r *bufio.Reader) (RecordingHeader, error) {
+	var header RecordingHeader
+	line, err := r.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return header, fmt.Errorf("reading recording: %w", err)
+	}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return header, fmt.Errorf("reading recording: %w", err)
+	}
+	if header.Version != 2 {
+		return header, fmt.Errorf("reading recording: unsupported asciicast version %d", header.Version)
+	}
+	return header, nil
+}
+
+// ReplayOptions control the playback of a recording. Speed scales the
+// time between events and MaxWait, if positive, caps it.
+type ReplayOptions struct {
+	Speed   float64
+	MaxWait time.Duration
+	Output  io.Writer
+	Context context.Context
+}
+
+// Replay writes the output of a recording to o.Output with its original
+// timing. It returns early once o.Context is done.
+func Replay(r io.Reader, o ReplayOptions) error {
+	in := bufio.NewReader(r)
+	if _, err := readRecordingHeader(in); err != nil {
+		return err
+	}
+	ctx := o.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	speed := o.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	previous := 0.0
+	for {
+		line, err := in.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			e, perr := parseRecordingEvent(line)
+			if perr != nil {
+				return perr
+			}
+			wait := time.Duration((e.Time - previous) / speed * float64(time.Second))
+			if o.MaxWait > 0 && wait > o.MaxWait {
+				wait = o.MaxWait
+			}
+			previous = e.Time
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			if e.Kind == "o" {
+				if _, err := io.WriteString(o.Output, e.Data); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading recording: %w", err)
+		}
+	}
+}
+
+func parseRecordingEvent(line []byte) (recordingEvent, error) {
+	var e recordingEvent
+	var fields []json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil || len(fields) != 3 {
+		return e, fmt.Errorf("reading recording: invalid event %q", strings.TrimSpace(string(line)))
+	}
+	if json.Unmarshal(fields[0], &e.Time) != nil || json.Unmarshal(fields[1], &e.Kind) != nil || json.Unmarshal(fields[2], &e.Data) != nil {
+		return e, fmt.Errorf("reading recording: invalid event %q", strings.TrimSpace(string(line)))
+	}
+	return e, nil
+}
@@ -2,37 +2,64 @@ package k8s
 
 import (
 	"context"
-	"fmt"
-	"os"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
-func GetPods(clientset kubernetes.Clientset, namespaceName string) *corev1.PodList {
+func GetPods(clientset kubernetes.Interface, namespaceName string) (*corev1.PodList, error) {
 	pods, err := clientset.CoreV1().Pods(namespaceName).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
-		fmt.Printf("Error listing pods: %v\n", err)
-		os.Exit(1)
+		return nil, wrapError("listing pods", err)
 	}
-	return pods
+	return pods, nil
 }
 
-func GetNamespaces(clientset kubernetes.Clientset) *corev1.NamespaceList {
+// ListPodPage lists up to limit pods matching the selector, starting at the
+// continue token of the previous page. An empty namespace lists the pods of
+// all namespaces.
+func ListPodPage(clientset kubernetes.Interface, namespaceName string, selector PodSelector, limit int64, continueToken string) (*corev1.PodList, error) {
+	pods, err := clientset.CoreV1().Pods(namespaceName).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: selector.Labels,
+		FieldSelector: selector.Fields,
+		Limit:         limit,
+		Continue:      continueToken,
+	})
+	if err != nil {
+		return nil, wrapError("listing pods", err)
+	}
+	return pods, nil
+}
+
+func GetNamespaces(clientset kubernetes.Interface) (*corev1.NamespaceList, error) {
 	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
-		fmt.Printf("Error listing pods: %v\n", err)
-		os.Exit(1)
+		return nil, wrapError("listing namespaces", err)
+	}
+	return namespaces, nil
+}
+
+func GetNodes(clientset kubernetes.Interface) (*corev1.NodeList, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, wrapError("listing nodes", err)
+	}
+	return nodes, nil
+}
+
+func GetPod(clientset kubernetes.Interface, namespaceName string, podName string) (*corev1.Pod, error) {
+	pod, err := clientset.CoreV1().Pods(namespaceName).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, wrapError("getting pod", err)
 	}
-	return namespaces
+	return pod, nil
 }
 
-func GetContainers(clientset kubernetes.Clientset, namespaceName string, podName string) []corev1.Container {
+func GetContainers(clientset kubernetes.Interface, namespaceName string, podName string) ([]PodContainer, error) {
 	pod, err := clientset.CoreV1().Pods(namespaceName).Get(context.TODO(), podName, metav1.GetOptions{})
 	if err != nil {
-		fmt.Printf("Error listing containers: %v\n", err)
-		os.Exit(1)
+		return nil, wrapError("listing containers", err)
 	}
-	return pod.Spec.Containers
+	return PodContainers(pod), nil
 }
@@ -0,0 +1,111 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodSelector narrows the pods of a namespace. The zero value selects all of
+// them.
+type PodSelector struct {
+	// Labels and Fields are label and field selectors, evaluated by the API
+	// server.
+	Labels string
+	Fields string
+	// Owner, if set, keeps only the pods the workload owns.
+	Owner *Workload
+}
+
+func (s PodSelector) matches(pod *corev1.Pod, controller ControllerFunc) bool {
+	return s.Owner == nil || s.Owner.Owns(pod, controller)
+}
+
+// And returns a selector for the pods that match both s and other.
+func (s PodSelector) And(other PodSelector) PodSelector {
+	if other.Owner == nil {
+		other.Owner = s.Owner
+	}
+	other.Labels = joinSelectors(s.Labels, other.Labels)
+	other.Fields = joinSelectors(s.Fields, other.Fields)
+	return other
+}
+
+// String returns the label and field selectors in the form ParseSelector
+// reads.
+func (s PodSelector) String() string {
+	return joinSelectors(s.Labels, s.Fields)
+}
+
+func joinSelectors(a string, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "," + b
+}
+
+// ParseSelector reads a comma-separated list of label and field selector
+// requirements, such as "app=api,tier!=cache,status.phase=Running".
+// Requirements on metadata., spec. and status. keys are field selectors, all
+// others label selectors.
+func ParseSelector(s string) (PodSelector, error) {
+	var labelTerms, fieldTerms []string
+	for _, term := range splitSelector(s) {
+		if term = strings.TrimSpace(term); term == "" {
+			continue
+		}
+		if isFieldTerm(term) {
+			fieldTerms = append(fieldTerms, term)
+		} else {
+			labelTerms = append(labelTerms, term)
+		}
+	}
+	selector := PodSelector{Labels: strings.Join(labelTerms, ","), Fields: strings.Join(fieldTerms, ",")}
+	if _, err := labels.Parse(selector.Labels); err != nil {
+		return PodSelector{}, fmt.Errorf("invalid label selector %q: %w", selector.Labels, err)
+	}
+	if _, err := fields.ParseSelector(selector.Fields); err != nil {
+		return PodSelector{}, fmt.Errorf("invalid field selector %q: %w", selector.Fields, err)
+	}
+	return selector, nil
+}
+
+// splitSelector splits at the commas that are not inside the value list of
+// a set-based requirement such as "env in (dev,test)".
+func splitSelector(s string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, s[start:])
+}
+
+func isFieldTerm(term string) bool {
+	key := strings.TrimLeft(term, "!")
+	if i := strings.IndexAny(key, "=! "); i >= 0 {
+		key = key[:i]
+	}
+	if strings.Contains(key, "/") {
+		return false
+	}
+	for _, prefix := range []string{"metadata.", "spec.", "status."} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package k8s
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		in      string
+		labels  string
+		fields  string
+		invalid bool
+	}{
+		{in: ""},
+		{in: "app=api", labels: "app=api"},
+		{in: "app=api,tier!=cache,status.phase=Running", labels: "app=api,tier!=cache", fields: "status.phase=Running"},
+		{in: " app = api , metadata.name!=api-0 ", labels: "app = api", fields: "metadata.name!=api-0"},
+		{in: "spec.nodeName=node-1,status.phase!=Failed", fields: "spec.nodeName=node-1,status.phase!=Failed"},
+		{in: "env in (dev,test),tier notin (cache),!canary,app", labels: "env in (dev,test),tier notin (cache),!canary,app"},
+		{in: "status.example.com/phase=Running", labels: "status.example.com/phase=Running"},
+		{in: "app=api,,", labels: "app=api"},
+		{in: "env in (dev,test", invalid: true},
+		{in: "app=a b", invalid: true},
+		{in: "=api", invalid: true},
+		{in: "app=api,tier!", invalid: true},
+		{in: "status.phase in (Running)", invalid: true},
+		{in: "spec.nodeName", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSelector(tt.in)
+			if tt.invalid {
+				if err == nil {
+					t.Errorf("parsed as %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Labels != tt.labels || got.Fields != tt.fields || got.Owner != nil {
+				t.Errorf("got labels %q and fields %q, want %q and %q", got.Labels, got.Fields, tt.labels, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package k8s
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// Session is a shell or a command to run in a container.
+type Session struct {
+	Namespace string
+	Pod       string
+	Container string
+	// Command is run instead of an interactive shell if set.
+	Command []string
+	// TTY runs the command interactively on the local terminal. Otherwise
+	// Stdin, if set, is passed to the command and its output is streamed to
+	// Stdout and Stderr.
+	TTY    bool
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Run runs the session. Shells and commands are both logged to AuditLog,
+// and the container is added to the recently used ones if it ran.
+func (s Session) Run(client Client) error {
+	err := s.run(client)
+	if err == nil || ExitCode(err) > 0 {
+		rememberLaunch(client, s.Namespace, s.Pod, s.Container)
+	}
+	return err
+}
+
+func (s Session) run(client Client) error {
+	if len(s.Command) == 0 {
+		return OpenShell(client, s.Namespace, s.Pod, s.Container)
+	}
+	start := time.Now()
+	err := s.runCommand(client)
+	auditSession(AuditEntry{Namespace: s.Namespace, Pod: s.Pod, Container: s.Container, Command: s.Command}, start, err)
+	return err
+}
+
+func (s Session) runCommand(client Client) error {
+	switch {
+	case s.TTY:
+		return client.Exec(s.Namespace, s.Pod, s.Container, s.Command)
+	default:
+		options := RunOptions{Command: s.Command, Stdin: s.Stdin, Stdout: s.Stdout, Stderr: s.Stderr}
+		if options.Stdout == nil {
+			options.Stdout = os.Stdout
+		}
+		if options.Stderr == nil {
+			options.Stderr = os.Stderr
+		}
+		return client.Run(s.Namespace, s.Pod, s.Container, options)
+	}
+}
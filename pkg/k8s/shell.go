@@ -2,79 +2,121 @@ package k8s
 
 import (
 	"context"
-	"flag"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
-	"k8s.io/client-go/util/homedir"
 	"k8s.io/kubectl/pkg/cmd/exec"
 	"k8s.io/kubectl/pkg/cmd/util/podcmd"
-	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/interrupt"
 )
 
 var clientset *kubernetes.Clientset
 
-func GetKubernetesClientset() *kubernetes.Clientset {
+func GetKubernetesClientset() (*kubernetes.Clientset, error) {
 	if clientset != nil {
-		return clientset
+		return clientset, nil
 	}
-	// Set up kubeconfig
-	home := homedir.HomeDir()
-	kubeconfig := flag.String("kubeconfig", fmt.Sprintf("%s/.kube/config", home), "path to the kubeconfig file")
-	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	config, err := restConfig()
 	if err != nil {
-		fmt.Printf("Error building kubeconfig: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
 	// Create Kubernetes client
 	c, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		fmt.Printf("Error creating Kubernetes client: %v\n", err)
-		os.Exit(1)
+		return nil, wrapError("creating kubernetes client", err)
 	}
 	clientset = c
-	return c
+	return c, nil
 }
 
-func OpenShell(clientset *kubernetes.Clientset, namespace, pod string, container string) {
-	for _, cmd := range [][]string{{"bash"}, {"ash"}, {"sh"}} {
-		if err := openSpecificShell(clientset, namespace, pod, container, cmd); err != nil {
-			fmt.Printf("Error opening shell: %v\n", err)
-		} else {
-			return
-		}
+// OpenShell opens an interactive shell in the container. The shell is
+// detected by probing the container once, and cached per image digest. Other
+// shells are only tried if the chosen one does not exist in the container.
+func OpenShell(client Client, namespace, pod string, container string) error {
+	start := time.Now()
+	beginShellRecording()
+	shell, err := openShell(client, namespace, pod, container)
+	endShellRecording(!errors.Is(err, ErrExecutableNotFound))
+	var command []string
+	if shell != "" {
+		command = []string{shell}
 	}
+	auditSession(AuditEntry{Namespace: namespace, Pod: pod, Container: container, Command: command, Shell: true}, start, err)
+	return err
 }
 
-func openSpecificShell(clientset *kubernetes.Clientset, namespace, podName string, container string, command []string) error {
-	// the following is mostly stolen from https://github.com/kubernetes/kubectl/blob/master/pkg/cmd/exec/exec.go#L305
-	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		clientcmd.NewDefaultClientConfigLoadingRules(),
-		&clientcmd.ConfigOverrides{},
-	)
-	restconfig, err := config.ClientConfig()
+// openShell does the work of OpenShell and returns the shell it ran last.
+func openShell(client Client, namespace, pod string, container string) (string, error) {
+	p, err := client.Pod(namespace, pod)
 	if err != nil {
-		panic(err)
+		return "", err
+	}
+	if err := CheckExecutable(p, container); err != nil {
+		return "", err
+	}
+
+	digest := shellCacheKey(imageDigest(p, container))
+	shells := fallbackShells()
+	if shell, ok := cachedShell(digest); ok {
+		shells = append([]string{shell}, fallbackShells()...)
+	} else if shell, err := detectShell(client, namespace, pod, container); err == nil {
+		shells = append([]string{shell}, fallbackShells()...)
 	}
-	restconfig.GroupVersion = &schema.GroupVersion{}
-	restconfig.NegotiatedSerializer = runtime.NewSimpleNegotiatedSerializer(runtime.SerializerInfo{})
 
+	tried := map[string]bool{}
+	last := ""
+	for _, shell := range shells {
+		if tried[shell] {
+			continue
+		}
+		tried[shell], last = true, shell
+		err = client.Exec(namespace, pod, container, []string{shell})
+		if !errors.Is(err, ErrExecutableNotFound) {
+			if err == nil || ExitCode(err) > 0 {
+				cacheShell(digest, shell)
+			}
+			return shell, err
+		}
+	}
+	return last, err
+}
+
+// openSpecificShell runs command in the container on the local terminal. If
+// parent is set, it runs after the terminal is restored, both when the
+// session ends and when ksh is interrupted.
+func openSpecificShell(clientset kubernetes.Interface, config *rest.Config, namespace, podName string, container string, command []string, parent *interrupt.Handler) error {
+	return openTerminal(clientset, config, namespace, podName, container, command, false, parent)
+}
+
+// attachContainer connects the local terminal to the process the container
+// runs, such as the shell of a debug container.
+func attachContainer(clientset kubernetes.Interface, config *rest.Config, namespace, podName string, container string) error {
+	return openTerminal(clientset, config, namespace, podName, container, nil, true, nil)
+}
+
+// openTerminal runs command in the container, or attaches to its process,
+// with the local terminal as its TTY.
+func openTerminal(clientset kubernetes.Interface, config *rest.Config, namespace, podName string, container string, command []string, attach bool, parent *interrupt.Handler) error {
+	// the following is mostly stolen from https://github.com/kubernetes/kubectl/blob/master/pkg/cmd/exec/exec.go#L305
+	var err error
 	streams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
 	p := exec.ExecOptions{
 		Executor: &exec.DefaultRemoteExecutor{},
-		Config:   restconfig,
+		Config:   execConfig(config),
 		StreamOptions: exec.StreamOptions{
-			IOStreams: streams,
-			TTY:       true,
+			IOStreams:       streams,
+			TTY:             true,
+			InterruptParent: parent,
 		},
 	}
 	p.Stdin = true
@@ -82,7 +124,7 @@ func openSpecificShell(clientset *kubernetes.Clientset, namespace, podName strin
 	if len(podName) != 0 {
 		p.Pod, err = clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
 		if err != nil {
-			return err
+			return wrapError("getting pod", err)
 		}
 	}
 	pod := p.Pod
@@ -101,6 +143,13 @@ func openSpecificShell(clientset *kubernetes.Clientset, namespace, podName strin
 		}
 		container = c.Name
 	}
+	if err := CheckExecutable(pod, container); err != nil {
+		return err
+	}
+	if attach {
+		// the process has drawn its prompt before the terminal is attached
+		fmt.Fprintln(os.Stderr, "If you don't see a command prompt, try pressing enter.")
+	}
 	t := p.SetupTTY()
 	var sizeQueue remotecommand.TerminalSizeQueue
 	if t.Raw {
@@ -112,25 +161,55 @@ func openSpecificShell(clientset *kubernetes.Clientset, namespace, podName strin
 		p.ErrOut = nil
 	}
 
+	// the size monitor needs the real terminal, so the output is only
+	// wrapped for the stream itself
+	out := &headWriter{w: p.Out}
+	var in io.Reader = p.In
+	var stdout, stderr io.Writer = out, p.ErrOut
+	rec, shared, err := sessionRecording(namespace, podName, container, command, t.GetSize())
+	if err != nil {
+		return err
+	}
+	if rec != nil {
+		if !shared {
+			defer func() {
+				if errors.Is(err, ErrExecutableNotFound) {
+					rec.discard()
+				} else {
+					_ = rec.Close()
+				}
+			}()
+		}
+		in, stdout = recordingReader{r: rec, in: in}, recordingWriter{r: rec, out: stdout}
+		if stderr != nil {
+			stderr = recordingWriter{r: rec, out: stderr}
+		}
+		if sizeQueue != nil {
+			sizeQueue = recordingSizeQueue{r: rec, queue: sizeQueue}
+		}
+	}
 	fn := func() error {
-		req := clientset.CoreV1().RESTClient().Post().
-			Resource("pods").
-			Name(podName).
-			Namespace(namespace).
-			SubResource("exec").
-			VersionedParams(&corev1.PodExecOptions{
+		var req *rest.Request
+		if attach {
+			req = attachRequest(clientset, namespace, podName, &corev1.PodAttachOptions{
+				Container: container,
+				Stdin:     p.Stdin,
+				Stdout:    p.Out != nil,
+				Stderr:    p.ErrOut != nil,
+				TTY:       t.Raw,
+			})
+		} else {
+			req = execRequest(clientset, namespace, podName, &corev1.PodExecOptions{
 				Container: container,
 				Command:   command,
 				Stdin:     p.Stdin,
 				Stdout:    p.Out != nil,
 				Stderr:    p.ErrOut != nil,
 				TTY:       t.Raw,
-			}, scheme.ParameterCodec)
-
-		return p.Executor.Execute(req.URL(), p.Config, p.In, p.Out, p.ErrOut, t.Raw, sizeQueue)
-	}
-	if err := t.Safe(fn); err != nil {
-		return err
+			})
+		}
+		return p.Executor.Execute(req.URL(), p.Config, in, stdout, stderr, t.Raw, sizeQueue)
 	}
-	return nil
+	err = execError(t.Safe(fn), out)
+	return err
 }
@@ -0,0 +1,88 @@
+package k8s_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/k8s/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func shellPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				Image: "app",
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		},
+	}
+}
+
+func TestOpenShell(t *testing.T) {
+	notFound := func(shell string) error {
+		return &k8s.Error{Kind: k8s.KindExecutableNotFound, Op: "opening shell", Err: fmt.Errorf("%s not found", shell)}
+	}
+	tests := []struct {
+		name    string
+		probe   string
+		missing []string
+		exit    int
+		want    []string
+	}{
+		{name: "detected shell", probe: "/bin/ash\n", want: []string{"/bin/ash"}},
+		{name: "probe fails", want: []string{"bash"}},
+		{name: "detected shell missing", probe: "/bin/ash\n", missing: []string{"/bin/ash", "bash"}, want: []string{"/bin/ash", "bash", "zsh"}},
+		{name: "no shell at all", missing: []string{"bash", "zsh", "ash", "sh"}, want: []string{"bash", "zsh", "ash", "sh"}},
+		{name: "exit code of the shell", exit: 127, want: []string{"bash"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			auditLog := k8s.AuditLog
+			k8s.AuditLog = ""
+			t.Cleanup(func() { k8s.AuditLog = auditLog })
+			client := fake.NewClient(shellPod())
+			client.RunFunc = func(_ fake.ExecCall, options k8s.RunOptions) error {
+				if tt.probe == "" {
+					return errors.New("no /bin/sh")
+				}
+				_, err := options.Stdout.Write([]byte(tt.probe))
+				return err
+			}
+			client.ExecFunc = func(call fake.ExecCall) error {
+				if slices.Contains(tt.missing, call.Command[0]) {
+					return notFound(call.Command[0])
+				}
+				if tt.exit > 0 {
+					return utilexec.CodeExitError{Err: errors.New("exit"), Code: tt.exit}
+				}
+				return nil
+			}
+
+			err := k8s.OpenShell(client, "payments", "api", "app")
+			var shells []string
+			for _, call := range client.Execs {
+				shells = append(shells, call.Command[0])
+			}
+			if !slices.Equal(shells, tt.want) {
+				t.Errorf("tried %v, want %v", shells, tt.want)
+			}
+			if len(tt.missing) == 4 && !errors.Is(err, k8s.ErrExecutableNotFound) {
+				t.Errorf("err = %v, want the shell not found", err)
+			}
+			if got := k8s.ExitCode(err); tt.exit > 0 && got != tt.exit {
+				t.Errorf("exit code = %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
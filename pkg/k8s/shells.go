@@ -0,0 +1,158 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Shells is the order in which shells are preferred. "$SHELL" stands for
+// the shell set in the container's environment, names without a slash are
+// looked up in /bin when probing and in the PATH when exec'ing.
+var Shells = []string{"$SHELL", "bash", "zsh", "ash", "sh"}
+
+var defaultShells = strings.Join(Shells, " ")
+
+// fallbackShells are tried in order when the shell of a container could not
+// be detected.
+func fallbackShells() []string {
+	var shells []string
+	for _, s := range Shells {
+		if s != "$SHELL" {
+			shells = append(shells, s)
+		}
+	}
+	return shells
+}
+
+// probeScript prints the first usable shell of Shells. It needs /bin/sh, so
+// it fails on images without one, in which case the fallback shells are
+// tried.
+func probeScript() string {
+	paths := make([]string, len(Shells))
+	for i, s := range Shells {
+		switch {
+		case s == "$SHELL":
+			paths[i] = `"$SHELL"`
+		case strings.Contains(s, "/"):
+			paths[i] = s
+		default:
+			paths[i] = "/bin/" + s
+		}
+	}
+	return `for s in ` + strings.Join(paths, " ") + `; do
+	if [ -n "$s" ] && [ -x "$s" ]; then echo "$s"; exit 0; fi
+done
+exit 1`
+}
+
+func detectShell(client Client, namespace string, pod string, container string) (string, error) {
+	var out bytes.Buffer
+	err := client.Run(namespace, pod, container, RunOptions{
+		Command: []string{"/bin/sh", "-c", probeScript()},
+		Stdout:  &out,
+	})
+	if err != nil {
+		return "", err
+	}
+	shell := strings.TrimSpace(out.String())
+	if shell == "" {
+		return "", &Error{Kind: KindExecutableNotFound, Op: "detecting shell"}
+	}
+	return shell, nil
+}
+
+// imageDigest returns the image ID the container runs with, which identifies
+// the image content regardless of its tag.
+func imageDigest(pod *corev1.Pod, container string) string {
+	for _, c := range PodContainers(pod) {
+		if c.Name == container && c.Status != nil {
+			return c.Status.ImageID
+		}
+	}
+	return ""
+}
+
+// shellCacheKey identifies the shell detected for an image. A shell order
+// other than the default one is part of the key, since it may pick another
+// shell in the same image.
+func shellCacheKey(digest string) string {
+	if digest == "" || strings.Join(Shells, " ") == defaultShells {
+		return digest
+	}
+	return digest + " " + strings.Join(Shells, " ")
+}
+
+var shellCache = struct {
+	sync.Mutex
+	loaded bool
+	shells map[string]string
+}{}
+
+func shellCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ksh", "shells.json"), nil
+}
+
+func loadShellCache() {
+	if shellCache.loaded {
+		return
+	}
+	shellCache.loaded = true
+	shellCache.shells = map[string]string{}
+	path, err := shellCachePath()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &shellCache.shells)
+}
+
+func cachedShell(digest string) (string, bool) {
+	if digest == "" {
+		return "", false
+	}
+	shellCache.Lock()
+	defer shellCache.Unlock()
+	loadShellCache()
+	shell, ok := shellCache.shells[digest]
+	return shell, ok
+}
+
+// cacheShell remembers the shell for the image. The cache is best effort,
+// failures to persist it are ignored.
+func cacheShell(digest string, shell string) {
+	if digest == "" {
+		return
+	}
+	shellCache.Lock()
+	defer shellCache.Unlock()
+	loadShellCache()
+	if shellCache.shells[digest] == shell {
+		return
+	}
+	shellCache.shells[digest] = shell
+	path, err := shellCachePath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(shellCache.shells)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0o644)
+}
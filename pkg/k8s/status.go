@@ -0,0 +1,195 @@
+package k8s
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodReason summarises the state of a pod the way kubectl's STATUS column
+// does, preferring container waiting and termination reasons over the phase.
+func PodReason(pod corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+	for _, s := range pod.Status.InitContainerStatuses {
+		switch {
+		case s.State.Terminated != nil && s.State.Terminated.ExitCode == 0:
+			continue
+		case s.State.Terminated != nil:
+			return "Init:" + terminatedReason(s.State.Terminated)
+		case s.State.Waiting != nil && s.State.Waiting.Reason != "" && s.State.Waiting.Reason != "PodInitializing":
+			return "Init:" + s.State.Waiting.Reason
+		default:
+			return "Init"
+		}
+	}
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.State.Waiting != nil && s.State.Waiting.Reason != "" {
+			reason = s.State.Waiting.Reason
+		} else if s.State.Terminated != nil {
+			reason = terminatedReason(s.State.Terminated)
+		}
+	}
+	return reason
+}
+
+func terminatedReason(t *corev1.ContainerStateTerminated) string {
+	if t.Reason != "" {
+		return t.Reason
+	}
+	if t.Signal != 0 {
+		return fmt.Sprintf("Signal:%d", t.Signal)
+	}
+	return fmt.Sprintf("ExitCode:%d", t.ExitCode)
+}
+
+// PodReadiness returns the number of ready containers and the total number
+// of regular containers of the pod.
+func PodReadiness(pod corev1.Pod) (int, int) {
+	ready := 0
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.Ready {
+			ready++
+		}
+	}
+	return ready, len(pod.Spec.Containers)
+}
+
+func PodRestarts(pod corev1.Pod) int32 {
+	var restarts int32
+	for _, s := range pod.Status.ContainerStatuses {
+		restarts += s.RestartCount
+	}
+	return restarts
+}
+
+// ContainerState returns the state of a container (Running, Waiting or
+// Terminated) together with the reason for the latter two.
+func ContainerState(status corev1.ContainerStatus) string {
+	switch {
+	case status.State.Running != nil:
+		return "Running"
+	case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+		return "Waiting:" + status.State.Waiting.Reason
+	case status.State.Waiting != nil:
+		return "Waiting"
+	case status.State.Terminated != nil:
+		return "Terminated:" + terminatedReason(status.State.Terminated)
+	default:
+		return "Unknown"
+	}
+}
+
+type ContainerKind int
+
+const (
+	ContainerRegular ContainerKind = iota
+	ContainerInit
+	ContainerEphemeral
+)
+
+func (k ContainerKind) String() string {
+	switch k {
+	case ContainerInit:
+		return "init"
+	case ContainerEphemeral:
+		return "ephemeral"
+	default:
+		return "container"
+	}
+}
+
+// PodContainer is a container of any kind together with its status, which
+// is nil while the kubelet has not reported one yet.
+type PodContainer struct {
+	Name   string
+	Image  string
+	Kind   ContainerKind
+	Status *corev1.ContainerStatus
+}
+
+// PodContainers returns the regular, init and ephemeral containers of the
+// pod, in that order.
+func PodContainers(pod *corev1.Pod) []PodContainer {
+	statuses := map[string]corev1.ContainerStatus{}
+	for _, list := range [][]corev1.ContainerStatus{
+		pod.Status.ContainerStatuses,
+		pod.Status.InitContainerStatuses,
+		pod.Status.EphemeralContainerStatuses,
+	} {
+		for _, s := range list {
+			statuses[s.Name] = s
+		}
+	}
+	out := []PodContainer{}
+	add := func(name, image string, kind ContainerKind) {
+		c := PodContainer{Name: name, Image: image, Kind: kind}
+		if s, ok := statuses[name]; ok {
+			c.Status = &s
+		}
+		out = append(out, c)
+	}
+	for _, c := range pod.Spec.Containers {
+		add(c.Name, c.Image, ContainerRegular)
+	}
+	for _, c := range pod.Spec.InitContainers {
+		add(c.Name, c.Image, ContainerInit)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		add(c.Name, c.Image, ContainerEphemeral)
+	}
+	return out
+}
+
+// CheckExecutable returns an ErrContainerNotRunning error if the named
+// container of the pod has terminated or not started yet.
+func CheckExecutable(pod *corev1.Pod, container string) error {
+	for _, c := range PodContainers(pod) {
+		if c.Name != container {
+			continue
+		}
+		switch {
+		case c.Status == nil:
+			return notRunning(c, "has not started yet")
+		case c.Status.State.Running != nil:
+			return nil
+		case c.Status.State.Terminated != nil:
+			return notRunning(c, "has terminated ("+terminatedReason(c.Status.State.Terminated)+")")
+		case c.Status.State.Waiting != nil && c.Status.State.Waiting.Reason != "":
+			return notRunning(c, "has not started yet ("+c.Status.State.Waiting.Reason+")")
+		default:
+			return notRunning(c, "has not started yet")
+		}
+	}
+	return &Error{Kind: KindNotFound, Op: "checking container", Err: fmt.Errorf("container %s not found in pod %s", container, pod.Name)}
+}
+
+func notRunning(c PodContainer, reason string) error {
+	return &Error{
+		Kind: KindContainerNotRunning,
+		Op:   "checking container",
+		Err:  fmt.Errorf("%s container %s %s, only running containers can be exec'd into", c.Kind, c.Name, reason),
+	}
+}
+
+// DefaultContainer returns the name of the only regular container, if there
+// is exactly one. Init and ephemeral containers, such as debuggers, do not
+// count.
+func DefaultContainer(containers []PodContainer) (string, bool) {
+	name := ""
+	for _, c := range containers {
+		if c.Kind != ContainerRegular {
+			continue
+		}
+		if name != "" {
+			return "", false
+		}
+		name = c.Name
+	}
+	return name, name != ""
+}
@@ -0,0 +1,144 @@
+package k8s
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+type PodEventType int
+
+const (
+	PodAdded PodEventType = iota
+	PodUpdated
+	PodDeleted
+)
+
+type PodEvent struct {
+	Type PodEventType
+	Pod  corev1.Pod
+}
+
+// PodWatcher keeps an up to date view of the pods of a namespace through a
+// shared informer and queues the events that changed it.
+type PodWatcher struct {
+	informer cache.SharedIndexInformer
+	selector PodSelector
+	// controller looks up the controllers of the pods' owners for selector.
+	controller ControllerFunc
+	stop       chan struct{}
+	stopOnce   sync.Once
+	notify     chan struct{}
+	waiting    atomic.Bool
+
+	mu      sync.Mutex
+	pending []PodEvent
+}
+
+func watchPods(clientset kubernetes.Interface, namespace string, selector PodSelector) (*PodWatcher, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.LabelSelector = selector.Labels
+			o.FieldSelector = selector.Fields
+		}))
+	w := &PodWatcher{
+		informer: factory.Core().V1().Pods().Informer(),
+		selector: selector,
+		controller: cachedControllers(func(namespace string, owner metav1.OwnerReference) (*metav1.OwnerReference, error) {
+			return GetController(clientset, namespace, owner)
+		}),
+		stop:   make(chan struct{}),
+		notify: make(chan struct{}, 1),
+	}
+	var listErr error
+	if err := w.informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+		if !w.informer.HasSynced() {
+			listErr = err
+			w.Stop()
+		}
+	}); err != nil {
+		return nil, wrapError("watching pods", err)
+	}
+	registration, err := w.informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				w.push(PodAdded, obj)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) { w.push(PodUpdated, obj) },
+		DeleteFunc: func(obj interface{}) { w.push(PodDeleted, obj) },
+	})
+	if err != nil {
+		return nil, wrapError("watching pods", err)
+	}
+
+	go w.informer.Run(w.stop)
+	if !cache.WaitForCacheSync(w.stop, registration.HasSynced) {
+		w.Stop()
+		return nil, wrapError("watching pods", listErr)
+	}
+	return w, nil
+}
+
+func (w *PodWatcher) push(t PodEventType, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || !w.selector.matches(pod, w.controller) {
+		return
+	}
+	w.mu.Lock()
+	w.pending = append(w.pending, PodEvent{Type: t, Pod: *pod})
+	w.mu.Unlock()
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Pods returns the current pods of the namespace that match the selector,
+// sorted by name.
+func (w *PodWatcher) Pods() []corev1.Pod {
+	objs := w.informer.GetStore().List()
+	pods := make([]corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		if pod, ok := obj.(*corev1.Pod); ok && w.selector.matches(pod, w.controller) {
+			pods = append(pods, *pod)
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	return pods
+}
+
+// Next blocks until new events arrive and returns them. Only one caller
+// waits at a time; concurrent callers and callers after Stop return
+// immediately with ok set to false.
+func (w *PodWatcher) Next() (events []PodEvent, ok bool) {
+	if !w.waiting.CompareAndSwap(false, true) {
+		return nil, false
+	}
+	defer w.waiting.Store(false)
+	select {
+	case <-w.notify:
+	case <-w.stop:
+		return nil, false
+	}
+	w.mu.Lock()
+	events, w.pending = w.pending, nil
+	w.mu.Unlock()
+	return events, true
+}
+
+func (w *PodWatcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
@@ -0,0 +1,269 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+type WorkloadKind int
+
+const (
+	Deployment WorkloadKind = iota
+	StatefulSet
+	DaemonSet
+	ReplicaSet
+	Job
+	CronJob
+)
+
+// String returns the kind as it appears in owner references.
+func (k WorkloadKind) String() string {
+	switch k {
+	case Deployment:
+		return "Deployment"
+	case StatefulSet:
+		return "StatefulSet"
+	case DaemonSet:
+		return "DaemonSet"
+	case ReplicaSet:
+		return "ReplicaSet"
+	case Job:
+		return "Job"
+	default:
+		return "CronJob"
+	}
+}
+
+// Workload is a controller that manages pods.
+type Workload struct {
+	Kind      WorkloadKind
+	Name      string
+	Namespace string
+	UID       types.UID
+	// Ready is the number of ready or, for jobs, completed pods out of
+	// Desired. Both are zero for CronJobs.
+	Ready   int32
+	Desired int32
+	// Status describes the workload beyond its readiness.
+	Status string
+	// Selector is the label selector of the pods. For CronJobs it selects
+	// the labels of the pod template of their jobs.
+	Selector string
+	Created  metav1.Time
+}
+
+// Pods returns a selector for the pods the workload owns.
+func (w Workload) Pods() PodSelector {
+	return PodSelector{Labels: w.Selector, Owner: &w}
+}
+
+// Owns tells whether the pod is controlled by the workload, directly or
+// through the ReplicaSets of a Deployment or the Jobs of a CronJob. The
+// controller of those is looked up with controller.
+func (w Workload) Owns(pod *corev1.Pod, controller ControllerFunc) bool {
+	c := metav1.GetControllerOf(pod)
+	if c == nil {
+		return false
+	}
+	switch w.Kind {
+	case Deployment, CronJob:
+		if parentKinds[c.Kind] != w.Kind.String() {
+			return false
+		}
+		parent, err := controller(pod.Namespace, *c)
+		return err == nil && parent != nil && parent.Kind == w.Kind.String() && parent.UID == w.UID
+	default:
+		return c.Kind == w.Kind.String() && c.UID == w.UID
+	}
+}
+
+// ControllerFunc looks up the controller of a ReplicaSet or Job, like
+// GetController.
+type ControllerFunc func(namespace string, owner metav1.OwnerReference) (*metav1.OwnerReference, error)
+
+// cachedControllers remembers what lookup found by the UID of the object,
+// since its controller does not change. Objects that are gone or may not be
+// read are remembered as having no controller; other errors are not.
+func cachedControllers(lookup ControllerFunc) ControllerFunc {
+	var mu sync.Mutex
+	found := map[types.UID]*metav1.OwnerReference{}
+	return func(namespace string, owner metav1.OwnerReference) (*metav1.OwnerReference, error) {
+		mu.Lock()
+		c, ok := found[owner.UID]
+		mu.Unlock()
+		if ok {
+			return c, nil
+		}
+		c, err := lookup(namespace, owner)
+		if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrForbidden) {
+			return nil, err
+		}
+		mu.Lock()
+		found[owner.UID] = c
+		mu.Unlock()
+		return c, nil
+	}
+}
+
+// GetController returns the controller of the ReplicaSet or Job the
+// owner reference points to, nil if it has none. Other kinds are not
+// looked up and have no controller.
+func GetController(clientset kubernetes.Interface, namespace string, owner metav1.OwnerReference) (*metav1.OwnerReference, error) {
+	var meta metav1.ObjectMeta
+	switch owner.Kind {
+	case "ReplicaSet":
+		r, err := clientset.AppsV1().ReplicaSets(namespace).Get(context.TODO(), owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, wrapError("getting replica set", err)
+		}
+		meta = r.ObjectMeta
+	case "Job":
+		j, err := clientset.BatchV1().Jobs(namespace).Get(context.TODO(), owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, wrapError("getting job", err)
+		}
+		meta = j.ObjectMeta
+	default:
+		return nil, nil
+	}
+	return metav1.GetControllerOf(&meta), nil
+}
+
+// GetWorkloads lists the workloads of a namespace, grouped by kind. Kinds
+// the user may not list are left out. ReplicaSets that belong to a
+// Deployment are left out as well, since their pods are found through it.
+func GetWorkloads(clientset kubernetes.Interface, namespaceName string) ([]Workload, error) {
+	ctx := context.TODO()
+	var workloads []Workload
+	var errs []error
+	skip := func(err error) bool {
+		if err == nil {
+			return false
+		}
+		if err = wrapError("listing workloads", err); !errors.Is(err, ErrForbidden) {
+			errs = append(errs, err)
+		}
+		return true
+	}
+
+	deployments, err := clientset.AppsV1().Deployments(namespaceName).List(ctx, metav1.ListOptions{})
+	if !skip(err) {
+		for _, d := range deployments.Items {
+			workloads = append(workloads, deploymentWorkload(d))
+		}
+	}
+	statefulSets, err := clientset.AppsV1().StatefulSets(namespaceName).List(ctx, metav1.ListOptions{})
+	if !skip(err) {
+		for _, s := range statefulSets.Items {
+			w := workload(StatefulSet, s.ObjectMeta, s.Spec.Selector)
+			w.Ready, w.Desired = s.Status.ReadyReplicas, replicas(s.Spec.Replicas)
+			w.Status = fmt.Sprintf("%d updated", s.Status.UpdatedReplicas)
+			workloads = append(workloads, w)
+		}
+	}
+	daemonSets, err := clientset.AppsV1().DaemonSets(namespaceName).List(ctx, metav1.ListOptions{})
+	if !skip(err) {
+		for _, d := range daemonSets.Items {
+			w := workload(DaemonSet, d.ObjectMeta, d.Spec.Selector)
+			w.Ready, w.Desired = d.Status.NumberReady, d.Status.DesiredNumberScheduled
+			w.Status = fmt.Sprintf("%d available", d.Status.NumberAvailable)
+			workloads = append(workloads, w)
+		}
+	}
+	replicaSets, err := clientset.AppsV1().ReplicaSets(namespaceName).List(ctx, metav1.ListOptions{})
+	if !skip(err) {
+		for _, r := range replicaSets.Items {
+			if c := metav1.GetControllerOf(&r); c != nil && c.Kind == "Deployment" {
+				continue
+			}
+			w := workload(ReplicaSet, r.ObjectMeta, r.Spec.Selector)
+			w.Ready, w.Desired = r.Status.ReadyReplicas, replicas(r.Spec.Replicas)
+			w.Status = fmt.Sprintf("%d available", r.Status.AvailableReplicas)
+			workloads = append(workloads, w)
+		}
+	}
+	jobs, err := clientset.BatchV1().Jobs(namespaceName).List(ctx, metav1.ListOptions{})
+	if !skip(err) {
+		for _, j := range jobs.Items {
+			workloads = append(workloads, jobWorkload(j))
+		}
+	}
+	cronJobs, err := clientset.BatchV1().CronJobs(namespaceName).List(ctx, metav1.ListOptions{})
+	if !skip(err) {
+		for _, c := range cronJobs.Items {
+			w := workload(CronJob, c.ObjectMeta, cronJobSelector(c))
+			w.Status = fmt.Sprintf("%s, %d active", c.Spec.Schedule, len(c.Status.Active))
+			if c.Spec.Suspend != nil && *c.Spec.Suspend {
+				w.Status = c.Spec.Schedule + ", suspended"
+			}
+			workloads = append(workloads, w)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	sort.SliceStable(workloads, func(i, j int) bool {
+		if workloads[i].Kind != workloads[j].Kind {
+			return workloads[i].Kind < workloads[j].Kind
+		}
+		return workloads[i].Name < workloads[j].Name
+	})
+	return workloads, nil
+}
+
+func workload(kind WorkloadKind, meta metav1.ObjectMeta, selector *metav1.LabelSelector) Workload {
+	w := Workload{Kind: kind, Name: meta.Name, Namespace: meta.Namespace, UID: meta.UID, Created: meta.CreationTimestamp}
+	if selector != nil {
+		if s, err := metav1.LabelSelectorAsSelector(selector); err == nil {
+			w.Selector = s.String()
+		}
+	}
+	return w
+}
+
+// cronJobSelector returns the selector of the jobs the CronJob creates or,
+// since that is usually left to the job controller, one for the labels of
+// their pod template.
+func cronJobSelector(c batchv1.CronJob) *metav1.LabelSelector {
+	if selector := c.Spec.JobTemplate.Spec.Selector; selector != nil {
+		return selector
+	}
+	return &metav1.LabelSelector{MatchLabels: c.Spec.JobTemplate.Spec.Template.Labels}
+}
+
+func deploymentWorkload(d appsv1.Deployment) Workload {
+	w := workload(Deployment, d.ObjectMeta, d.Spec.Selector)
+	w.Ready, w.Desired = d.Status.ReadyReplicas, replicas(d.Spec.Replicas)
+	w.Status = fmt.Sprintf("%d up-to-date, %d available", d.Status.UpdatedReplicas, d.Status.AvailableReplicas)
+	return w
+}
+
+func jobWorkload(j batchv1.Job) Workload {
+	w := workload(Job, j.ObjectMeta, j.Spec.Selector)
+	w.Ready, w.Desired = j.Status.Succeeded, replicas(j.Spec.Completions)
+	w.Status = "Running"
+	for _, c := range j.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			w.Status = string(c.Type)
+		}
+	}
+	return w
+}
+
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
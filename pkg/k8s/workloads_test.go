@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func meta(name string, uid types.UID, labels map[string]string, controller ...metav1.OwnerReference) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: "payments", Name: name, UID: uid, Labels: labels, OwnerReferences: controller}
+}
+
+func controlledBy(kind string, name string, uid types.UID) metav1.OwnerReference {
+	isController := true
+	return metav1.OwnerReference{Kind: kind, Name: name, UID: uid, Controller: &isController}
+}
+
+func TestWorkloadPods(t *testing.T) {
+	web, backup := map[string]string{"app": "web"}, map[string]string{"app": "backup"}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: meta("web", "web", nil),
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: web}},
+		},
+		&appsv1.ReplicaSet{ObjectMeta: meta("web-5d8f", "web-5d8f", web, controlledBy("Deployment", "web", "web"))},
+		// left behind by an earlier deployment of the same name
+		&appsv1.ReplicaSet{ObjectMeta: meta("web-7c9a", "web-7c9a", web, controlledBy("Deployment", "web", "web-before"))},
+		&appsv1.ReplicaSet{ObjectMeta: meta("web-canary", "web-canary", web)},
+		&corev1.Pod{ObjectMeta: meta("web-5d8f-a", "", web, controlledBy("ReplicaSet", "web-5d8f", "web-5d8f"))},
+		&corev1.Pod{ObjectMeta: meta("web-7c9a-b", "", web, controlledBy("ReplicaSet", "web-7c9a", "web-7c9a"))},
+		&corev1.Pod{ObjectMeta: meta("web-canary-c", "", web, controlledBy("ReplicaSet", "web-canary", "web-canary"))},
+		&batchv1.CronJob{
+			ObjectMeta: meta("backup", "backup", nil),
+			Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: backup}},
+			}}},
+		},
+		&batchv1.Job{ObjectMeta: meta("backup-28712", "backup-28712", nil, controlledBy("CronJob", "backup", "backup"))},
+		&batchv1.Job{ObjectMeta: meta("backup-manual", "backup-manual", nil)},
+		&corev1.Pod{ObjectMeta: meta("backup-28712-x", "", backup, controlledBy("Job", "backup-28712", "backup-28712"))},
+		&corev1.Pod{ObjectMeta: meta("backup-manual-y", "", backup, controlledBy("Job", "backup-manual", "backup-manual"))},
+		&corev1.Pod{ObjectMeta: meta("db-0", "", map[string]string{"app": "db"})},
+	)
+	workloads, err := GetWorkloads(clientset, "payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		kind     WorkloadKind
+		name     string
+		selector string
+		pods     []string
+	}{
+		{kind: Deployment, name: "web", selector: "app=web", pods: []string{"web-5d8f-a"}},
+		{kind: ReplicaSet, name: "web-canary", pods: []string{"web-canary-c"}},
+		{kind: Job, name: "backup-manual", pods: []string{"backup-manual-y"}},
+		{kind: CronJob, name: "backup", selector: "app=backup", pods: []string{"backup-28712-x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind.String()+"/"+tt.name, func(t *testing.T) {
+			i := slices.IndexFunc(workloads, func(w Workload) bool { return w.Kind == tt.kind && w.Name == tt.name })
+			if i < 0 {
+				t.Fatalf("workload not listed in %v", workloads)
+			}
+			if got := workloads[i].Selector; got != tt.selector {
+				t.Errorf("selector = %q, want %q", got, tt.selector)
+			}
+			watcher, err := watchPods(clientset, "payments", workloads[i].Pods())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer watcher.Stop()
+			var names []string
+			for _, pod := range watcher.Pods() {
+				names = append(names, pod.Name)
+			}
+			if !slices.Equal(names, tt.pods) {
+				t.Errorf("pods = %v, want %v", names, tt.pods)
+			}
+		})
+	}
+}
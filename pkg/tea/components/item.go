@@ -7,17 +7,119 @@ import (
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/samox73/ksh/pkg/tea/styles"
 )
 
+type Marker int
+
+const (
+	MarkerNone Marker = iota
+	MarkerAdded
+	MarkerTerminating
+	MarkerDeleted
+)
+
+func (m Marker) View() string {
+	switch m {
+	case MarkerAdded:
+		return styles.AddedStyle.Render("new")
+	case MarkerTerminating:
+		return styles.TerminatingStyle.Render("terminating")
+	case MarkerDeleted:
+		return styles.DeletedStyle.Render("deleted")
+	default:
+		return ""
+	}
+}
+
+type Health int
+
+const (
+	HealthUnknown Health = iota
+	HealthOK
+	HealthWarning
+	HealthFailing
+)
+
+func (h Health) Style() lipgloss.Style {
+	switch h {
+	case HealthOK:
+		return styles.HealthyStyle
+	case HealthWarning:
+		return styles.WarningStyle
+	case HealthFailing:
+		return styles.FailingStyle
+	default:
+		return lipgloss.NewStyle()
+	}
+}
+
 type Item struct {
-	Labels map[string]string
-	Name   string
+	Labels  map[string]string
+	Name    string
+	Marker  Marker
+	Columns []string
+	Health  Health
+	// Checked marks items that were picked for an action on several items.
+	Checked bool
 }
 
 func (i Item) FilterValue() string { return i.Name }
 
-type ItemDelegate struct{}
+// ItemDelegate renders an item as its name followed by its columns, padded
+// so that the columns of all items line up under Headers.
+type ItemDelegate struct {
+	Headers []string
+	widths  []int
+}
+
+func NewItemDelegate(headers []string, items []list.Item) ItemDelegate {
+	d := ItemDelegate{Headers: headers}
+	if len(headers) == 0 {
+		return d
+	}
+	d.widths = make([]int, len(headers))
+	for j, h := range headers {
+		d.widths[j] = lipgloss.Width(h)
+	}
+	for _, listItem := range items {
+		i, ok := listItem.(Item)
+		if !ok {
+			continue
+		}
+		d.widths[0] = max(d.widths[0], lipgloss.Width(i.Name))
+		for j, c := range i.Columns {
+			if j+1 < len(d.widths) {
+				d.widths[j+1] = max(d.widths[j+1], lipgloss.Width(c))
+			}
+		}
+	}
+	return d
+}
+
+// Header renders the column titles aligned with the rendered items.
+func (d ItemDelegate) Header() string {
+	if len(d.Headers) == 0 {
+		return ""
+	}
+	return styles.HeaderStyle.Render(pad(d.Headers[0], d.widths[0]) + "  " + d.columns(d.Headers[1:]))
+}
+
+func (d ItemDelegate) columns(columns []string) string {
+	cells := make([]string, len(columns))
+	for j, c := range columns {
+		if j+1 < len(d.widths) {
+			c = pad(c, d.widths[j+1])
+		}
+		cells[j] = c
+	}
+	return strings.Join(cells, "  ")
+}
+
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-lipgloss.Width(s)))
+}
 
 func (d ItemDelegate) Height() int                             { return 1 }
 func (d ItemDelegate) Spacing() int                            { return 0 }
@@ -33,5 +135,15 @@ func (d ItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 			return styles.SelectedItemStyle.Render("> " + strings.Join(s, " "))
 		}
 	}
-	fmt.Fprint(w, fn(i.Name))
+	out := fn(i.Name)
+	if len(d.widths) > 0 {
+		out = fn(pad(i.Name, d.widths[0])) + "  " + i.Health.Style().Render(d.columns(i.Columns))
+	}
+	if i.Marker != MarkerNone {
+		out += " " + i.Marker.View()
+	}
+	if i.Checked {
+		out += " " + styles.HighlightStyle.Render("selected")
+	}
+	fmt.Fprint(w, out)
 }
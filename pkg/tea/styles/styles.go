@@ -14,6 +14,19 @@ var (
 	PaginationStyle      = list.DefaultStyles().PaginationStyle.PaddingLeft(4)
 	HelpStyle            = list.DefaultStyles().HelpStyle.PaddingLeft(4).PaddingBottom(1)
 	QuitTextStyle        = lipgloss.NewStyle().Margin(1, 0, 2, 4)
+	AddedStyle           = lipgloss.NewStyle().Foreground(lipgloss.Color("#5fd75f"))
+	TerminatingStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#d7af5f"))
+	DeletedStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5f5f")).Strikethrough(true)
+	HeaderStyle          = lipgloss.NewStyle().PaddingLeft(4).Bold(true).Foreground(lipgloss.Color("245"))
+	TableHeaderBarStyle  = lipgloss.NewStyle()
+	TableHeaderStyle     = lipgloss.NewStyle()
+	HealthyStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#5fd75f"))
+	WarningStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#d7af5f"))
+	FailingStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5f5f"))
+	StatusStyle          = lipgloss.NewStyle().Margin(0, 0, 0, 2).Foreground(lipgloss.Color("#d7af5f"))
+	HighlightStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#000000")).Background(lipgloss.Color("#ff895e"))
+	PromptStyle          = lipgloss.NewStyle().Margin(1, 0, 1, 2).Padding(0, 1).Border(lipgloss.NormalBorder(), true)
+	ErrorStyle           = lipgloss.NewStyle().Margin(1, 0, 1, 2).Padding(0, 1).Border(lipgloss.NormalBorder(), true).BorderForeground(lipgloss.Color("#ff5f5f")).Width(76)
 	LogoForegroundStyles = []lipgloss.Style{
 		lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5f00")).Background(lipgloss.Color("#ff5f00")),
 		lipgloss.NewStyle().Foreground(lipgloss.Color("#e65400")).Background(lipgloss.Color("#e65400")),
@@ -32,6 +45,41 @@ var (
 	}
 )
 
+// Theme holds the colours of ksh, as hex values such as #ff895e or ANSI
+// colour numbers such as 245.
+type Theme struct {
+	Accent  string
+	Healthy string
+	Warning string
+	Failing string
+	Muted   string
+}
+
+// DefaultTheme is the theme the styles start out with.
+var DefaultTheme = Theme{
+	Accent:  "#ff895e",
+	Healthy: "#5fd75f",
+	Warning: "#d7af5f",
+	Failing: "#ff5f5f",
+	Muted:   "245",
+}
+
+// ApplyTheme sets the colours of the styles. It must be called before the
+// first view is built.
+func ApplyTheme(t Theme) {
+	SelectedItemStyle = SelectedItemStyle.Foreground(lipgloss.Color(t.Accent))
+	HighlightStyle = HighlightStyle.Background(lipgloss.Color(t.Accent))
+	AddedStyle = AddedStyle.Foreground(lipgloss.Color(t.Healthy))
+	HealthyStyle = HealthyStyle.Foreground(lipgloss.Color(t.Healthy))
+	TerminatingStyle = TerminatingStyle.Foreground(lipgloss.Color(t.Warning))
+	WarningStyle = WarningStyle.Foreground(lipgloss.Color(t.Warning))
+	StatusStyle = StatusStyle.Foreground(lipgloss.Color(t.Warning))
+	DeletedStyle = DeletedStyle.Foreground(lipgloss.Color(t.Failing))
+	FailingStyle = FailingStyle.Foreground(lipgloss.Color(t.Failing))
+	ErrorStyle = ErrorStyle.BorderForeground(lipgloss.Color(t.Failing))
+	HeaderStyle = HeaderStyle.Foreground(lipgloss.Color(t.Muted))
+}
+
 func GetBanner(banner string) string {
 	trimmedBanner := strings.TrimSpace(banner)
 	var finalBanner strings.Builder
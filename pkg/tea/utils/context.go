@@ -2,20 +2,16 @@ package utils
 
 import (
 	"fmt"
-	"path/filepath"
 
 	"github.com/charmbracelet/lipgloss"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+	"github.com/samox73/ksh/pkg/k8s"
 )
 
 func ViewContext() string {
-	kubeconfig := filepath.Join(homedir.HomeDir(), ".kube", "config")
-	config := clientcmd.GetConfigFromFileOrDie(kubeconfig)
-	if config != nil {
-		l := fmt.Sprintf("context: %s", config.CurrentContext)
-		return lipgloss.NewStyle().Margin(0, 0, 0, 2).Render(l)
-	} else {
+	context, err := k8s.CurrentContext()
+	if err != nil {
 		return ""
 	}
-}
\ No newline at end of file
+	l := fmt.Sprintf("context: %s", context)
+	return lipgloss.NewStyle().Margin(0, 0, 0, 2).Render(l)
+}
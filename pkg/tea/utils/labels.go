@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/tea/styles"
+)
+
+func ViewLabels(labels map[string]string) string {
+	return ViewSelectableLabels(labels, -1)
+}
+
+// ViewSelectableLabels renders the labels like ViewLabels and highlights
+// the one at index cursor of LabelKeys.
+func ViewSelectableLabels(labels map[string]string, cursor int) string {
+	l := ""
+	keys := LabelKeys(labels)
+	longestKeyLength := 0
+	for _, k := range keys {
+		if len(k) > longestKeyLength {
+			longestKeyLength = len(k)
+		}
+	}
+	for j, k := range keys {
+		line := fmt.Sprintf("%*s: %s", longestKeyLength, k, labels[k])
+		if j == cursor {
+			line = styles.HighlightStyle.Render(line)
+		}
+		l += line
+		if j != len(labels)-1 {
+			l += "\n"
+		}
+	}
+	return lipgloss.NewStyle().Margin(0, 0, 0, 2).Border(lipgloss.NormalBorder(), true).Render(l)
+}
+
+// LabelKeys returns the keys of the labels in the order they are shown.
+func LabelKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
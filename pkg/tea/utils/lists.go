@@ -1,16 +1,46 @@
 package utils
 
 import (
+	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samox73/ksh/pkg/format"
+	"github.com/samox73/ksh/pkg/k8s"
 	"github.com/samox73/ksh/pkg/tea/components"
 	"github.com/samox73/ksh/pkg/tea/styles"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
 )
 
+var (
+	namespaceHeaders = []string{"NAME", "STATUS", "AGE"}
+	podHeaders       = []string{"NAME", "STATUS", "READY", "RESTARTS", "AGE", "NODE", "IP"}
+	globalPodHeaders = []string{"NAME", "NAMESPACE", "STATUS", "READY", "RESTARTS", "AGE", "NODE", "IP"}
+	containerHeaders = []string{"NAME", "KIND", "IMAGE", "STATE", "RESTARTS"}
+	portHeaders      = []string{"PORT", "NAME", "PROTOCOL", "CONTAINER"}
+	forwardHeaders   = []string{"LOCAL", "TARGET", "STATUS", "SENT", "RECEIVED", "AGE"}
+	fileHeaders      = []string{"NAME", "TYPE", "SIZE", "MODE", "MODIFIED"}
+	workloadHeaders  = []string{"NAME", "READY", "STATUS", "AGE"}
+	nodeHeaders      = []string{"NAME", "STATUS", "ROLES", "VERSION", "CPU", "MEMORY", "PODS", "AGE"}
+	historyHeaders   = []string{"TARGET", "STARTED", "CONTEXT", "COMMAND", "EXIT", "DURATION"}
+	launchHeaders    = []string{"TARGET", "CONTEXT", "KIND", "LAST USED"}
+)
+
+// ListHeight is the most rows a list takes up before the window size is
+// known, including its header and help.
+var ListHeight = 20
+
+// AllPods is the name of the first row of the workload list, which stands
+// for all pods of the namespace.
+const AllPods = "<all pods>"
+
 func listFromItems(items []list.Item) list.Model {
-	length := MinInt(len(items)+7, 20)
+	length := MinInt(len(items)+7, ListHeight)
 	l := list.New(items, components.ItemDelegate{}, 60, length)
 	l.SetShowStatusBar(false)
 	l.SetShowTitle(false)
@@ -21,9 +51,57 @@ func listFromItems(items []list.Item) list.Model {
 	return l
 }
 
+// tableFromItems builds a list whose items render as aligned columns, with
+// the column headers shown in place of the list title.
+func tableFromItems(headers []string, items []list.Item) list.Model {
+	l := listFromItems(items)
+	l.Styles.TitleBar = styles.TableHeaderBarStyle
+	l.Styles.Title = styles.TableHeaderStyle
+	l.SetShowTitle(true)
+	setTableItems(&l, headers, items)
+	return l
+}
+
+func setTableItems(l *list.Model, headers []string, items []list.Item) tea.Cmd {
+	delegate := components.NewItemDelegate(headers, items)
+	l.SetDelegate(delegate)
+	l.Title = delegate.Header()
+	return l.SetItems(items)
+}
+
+func age(t metav1.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t.Time))
+}
+
+func BuildContextList(contexts []k8s.KubeContext) list.Model {
+	items := buildContextItems(contexts)
+	l := listFromItems(items)
+	for i, c := range contexts {
+		if c.Current {
+			l.Select(i)
+		}
+	}
+	return l
+}
+
+func buildContextItems(contexts []k8s.KubeContext) []list.Item {
+	out := make([]list.Item, len(contexts))
+	for i, c := range contexts {
+		out[i] = components.Item{Name: c.Name, Labels: map[string]string{
+			"cluster":   c.Cluster,
+			"user":      c.User,
+			"namespace": c.Namespace,
+		}}
+	}
+	return out
+}
+
 func BuildNamespaceList(namespaces []corev1.Namespace) list.Model {
 	items := buildNamespaceItems(namespaces)
-	return listFromItems(items)
+	return tableFromItems(namespaceHeaders, items)
 }
 
 func buildNamespaceItems(namespaces []corev1.Namespace) []list.Item {
@@ -32,39 +110,392 @@ func buildNamespaceItems(namespaces []corev1.Namespace) []list.Item {
 	})
 	out := make([]list.Item, len(namespaces))
 	for i, ns := range namespaces {
-		out[i] = components.Item{Name: ns.Name}
+		health := components.HealthOK
+		if ns.Status.Phase != corev1.NamespaceActive {
+			health = components.HealthWarning
+		}
+		out[i] = components.Item{
+			Name:    ns.Name,
+			Columns: []string{string(ns.Status.Phase), age(ns.CreationTimestamp)},
+			Health:  health,
+		}
 	}
 	return out
 }
 
+// BuildWorkloadList lists the workloads as kind/name, below a row for all
+// pods of the namespace.
+func BuildWorkloadList(workloads []k8s.Workload) list.Model {
+	items := []list.Item{components.Item{Name: AllPods, Columns: []string{"", "", ""}}}
+	for _, w := range workloads {
+		ready := fmt.Sprintf("%d/%d", w.Ready, w.Desired)
+		if w.Kind == k8s.CronJob {
+			ready = "-"
+		}
+		items = append(items, components.Item{
+			Name:    WorkloadName(w),
+			Columns: []string{ready, w.Status, age(w.Created)},
+			Health:  workloadHealth(w),
+		})
+	}
+	return tableFromItems(workloadHeaders, items)
+}
+
+// WorkloadName names a workload the way kubectl does, e.g. deployment/web.
+func WorkloadName(w k8s.Workload) string {
+	return strings.ToLower(w.Kind.String()) + "/" + w.Name
+}
+
+func workloadHealth(w k8s.Workload) components.Health {
+	switch {
+	case w.Status == "Failed":
+		return components.HealthFailing
+	case w.Kind == k8s.CronJob, w.Desired == 0:
+		return components.HealthUnknown
+	case w.Ready >= w.Desired, w.Status == "Complete":
+		return components.HealthOK
+	case w.Ready == 0 && w.Kind != k8s.Job:
+		return components.HealthFailing
+	default:
+		return components.HealthWarning
+	}
+}
+
+// BuildNodeList lists the nodes with their allocatable resources.
+func BuildNodeList(nodes []corev1.Node) list.Model {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	items := make([]list.Item, len(nodes))
+	for i, node := range nodes {
+		conditions := k8s.NodeConditions(node)
+		roles := strings.Join(k8s.NodeRoles(node), ",")
+		if roles == "" {
+			roles = "<none>"
+		}
+		allocatable := node.Status.Allocatable
+		health := components.HealthOK
+		switch {
+		case conditions[0] != "Ready":
+			health = components.HealthFailing
+		case len(conditions) > 1:
+			health = components.HealthWarning
+		}
+		items[i] = components.Item{
+			Name:   node.Name,
+			Labels: node.Labels,
+			Columns: []string{
+				strings.Join(conditions, ","),
+				roles,
+				node.Status.NodeInfo.KubeletVersion,
+				allocatable.Cpu().String(),
+				format.ByteSize(allocatable.Memory().Value()),
+				allocatable.Pods().String(),
+				age(node.CreationTimestamp),
+			},
+			Health: health,
+		}
+	}
+	return tableFromItems(nodeHeaders, items)
+}
+
 func BuildPodList(pods []corev1.Pod) list.Model {
-	items := buildPodItems(pods)
-	return listFromItems(items)
+	items := buildPodItems(pods, nil, nil, false)
+	return tableFromItems(podHeaders, items)
+}
+
+// SetPodItems replaces the pods shown in l. Pods that are being deleted are
+// marked as terminating unless markers assigns them a different marker.
+// The pods in checked are shown as selected.
+func SetPodItems(l *list.Model, pods []corev1.Pod, markers map[string]components.Marker, checked map[string]bool) tea.Cmd {
+	return setTableItems(l, podHeaders, buildPodItems(pods, markers, checked, false))
+}
+
+// BuildGlobalPodList lists pods of several namespaces, sorted by namespace,
+// with the namespace in the first column.
+func BuildGlobalPodList(pods []corev1.Pod) list.Model {
+	return tableFromItems(globalPodHeaders, buildPodItems(pods, nil, nil, true))
+}
+
+func SetGlobalPodItems(l *list.Model, pods []corev1.Pod) tea.Cmd {
+	return setTableItems(l, globalPodHeaders, buildPodItems(pods, nil, nil, true))
 }
 
-func buildPodItems(pods []corev1.Pod) []list.Item {
+func buildPodItems(pods []corev1.Pod, markers map[string]components.Marker, checked map[string]bool, withNamespace bool) []list.Item {
 	sort.Slice(pods, func(i, j int) bool {
+		if withNamespace && pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
 		return pods[i].Name < pods[j].Name
 	})
 	out := make([]list.Item, len(pods))
 	for i, pod := range pods {
-		out[i] = components.Item{Name: pod.Name, Labels: pod.Labels}
+		marker, ok := markers[pod.Name]
+		if !ok && pod.DeletionTimestamp != nil {
+			marker = components.MarkerTerminating
+		}
+		reason := k8s.PodReason(pod)
+		ready, total := k8s.PodReadiness(pod)
+		columns := []string{
+			reason,
+			fmt.Sprintf("%d/%d", ready, total),
+			fmt.Sprint(k8s.PodRestarts(pod)),
+			age(pod.CreationTimestamp),
+			pod.Spec.NodeName,
+			pod.Status.PodIP,
+		}
+		if withNamespace {
+			columns = append([]string{pod.Namespace}, columns...)
+		}
+		out[i] = components.Item{
+			Name:    pod.Name,
+			Labels:  pod.Labels,
+			Marker:  marker,
+			Columns: columns,
+			Health:  podHealth(pod, reason, ready, total),
+			Checked: checked[pod.Name],
+		}
 	}
 	return out
 }
 
-func BuildContainerList(pods []corev1.Container) list.Model {
-	items := buildContainerItems(pods)
-	return listFromItems(items)
+func podHealth(pod corev1.Pod, reason string, ready int, total int) components.Health {
+	switch {
+	case pod.Status.Phase == corev1.PodFailed, strings.Contains(reason, "BackOff"),
+		strings.Contains(reason, "Err"), strings.Contains(reason, "Error"), reason == "OOMKilled":
+		return components.HealthFailing
+	case pod.Status.Phase == corev1.PodSucceeded:
+		return components.HealthUnknown
+	case pod.Status.Phase == corev1.PodRunning && ready == total && pod.DeletionTimestamp == nil:
+		return components.HealthOK
+	default:
+		return components.HealthWarning
+	}
 }
 
-func buildContainerItems(pods []corev1.Container) []list.Item {
-	sort.Slice(pods, func(i, j int) bool {
-		return pods[i].Name < pods[j].Name
+func BuildContainerList(containers []k8s.PodContainer) list.Model {
+	items := buildContainerItems(containers)
+	return tableFromItems(containerHeaders, items)
+}
+
+func SetContainerItems(l *list.Model, containers []k8s.PodContainer) tea.Cmd {
+	return setTableItems(l, containerHeaders, buildContainerItems(containers))
+}
+
+// buildContainerItems groups the containers by kind and sorts them by name
+// within each group.
+func buildContainerItems(containers []k8s.PodContainer) []list.Item {
+	sort.SliceStable(containers, func(i, j int) bool {
+		if containers[i].Kind != containers[j].Kind {
+			return containers[i].Kind < containers[j].Kind
+		}
+		return containers[i].Name < containers[j].Name
 	})
-	out := make([]list.Item, len(pods))
-	for i, pod := range pods {
-		out[i] = components.Item{Name: pod.Name}
+	out := make([]list.Item, len(containers))
+	for i, container := range containers {
+		state := "Unknown"
+		var restarts int32
+		if container.Status != nil {
+			state = k8s.ContainerState(*container.Status)
+			restarts = container.Status.RestartCount
+		}
+		out[i] = components.Item{
+			Name:    container.Name,
+			Columns: []string{container.Kind.String(), container.Image, state, fmt.Sprint(restarts)},
+			Health:  containerHealth(container),
+		}
+	}
+	return out
+}
+
+func containerHealth(container k8s.PodContainer) components.Health {
+	status := container.Status
+	switch {
+	case status == nil:
+		return components.HealthUnknown
+	case status.State.Running != nil && (status.Ready || container.Kind != k8s.ContainerRegular):
+		return components.HealthOK
+	case status.State.Terminated != nil && status.State.Terminated.ExitCode == 0:
+		return components.HealthUnknown
+	case status.State.Terminated != nil,
+		status.State.Waiting != nil && strings.Contains(status.State.Waiting.Reason, "BackOff"):
+		return components.HealthFailing
+	default:
+		return components.HealthWarning
+	}
+}
+
+// BuildPortList lists the ports declared by the containers of a pod. Ports
+// are shared by all containers of the pod, so each port is listed once.
+func BuildPortList(pod *corev1.Pod) list.Model {
+	var items []list.Item
+	seen := map[int32]bool{}
+	for _, c := range pod.Spec.Containers {
+		for _, port := range c.Ports {
+			if seen[port.ContainerPort] {
+				continue
+			}
+			seen[port.ContainerPort] = true
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = corev1.ProtocolTCP
+			}
+			health := components.HealthOK
+			if protocol != corev1.ProtocolTCP {
+				health = components.HealthUnknown
+			}
+			items = append(items, components.Item{
+				Name:    fmt.Sprint(port.ContainerPort),
+				Columns: []string{port.Name, string(protocol), c.Name},
+				Health:  health,
+			})
+		}
+	}
+	return tableFromItems(portHeaders, items)
+}
+
+func BuildForwardList(forwards []*k8s.Forward) list.Model {
+	return tableFromItems(forwardHeaders, buildForwardItems(forwards))
+}
+
+func SetForwardItems(l *list.Model, forwards []*k8s.Forward) tea.Cmd {
+	return setTableItems(l, forwardHeaders, buildForwardItems(forwards))
+}
+
+func buildForwardItems(forwards []*k8s.Forward) []list.Item {
+	out := make([]list.Item, len(forwards))
+	for i, f := range forwards {
+		status, health := "Active", components.HealthOK
+		select {
+		case <-f.Done():
+			status, health = "Stopped", components.HealthUnknown
+			if err := f.Err(); err != nil {
+				status, health = err.Error(), components.HealthFailing
+			}
+		default:
+		}
+		out[i] = components.Item{
+			Name: fmt.Sprintf("localhost:%d", f.LocalPort),
+			Columns: []string{
+				fmt.Sprintf("%s/%s:%d", f.Namespace, f.Pod, f.RemotePort),
+				status,
+				format.ByteSize(f.Sent()),
+				format.ByteSize(f.Received()),
+				duration.HumanDuration(time.Since(f.Started)),
+			},
+			Health: health,
+		}
+	}
+	return out
+}
+
+// BuildHistoryList lists the entries of the audit log, newest first. The
+// details of each entry are in its labels.
+func BuildHistoryList(entries []k8s.AuditEntry) list.Model {
+	items := make([]list.Item, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		command := strings.ReplaceAll(strings.Join(e.Command, " "), "\n", " ")
+		labels := map[string]string{"context": e.Context, "server": e.Server, "user": e.User, "command": command}
+		if e.Shell {
+			command = "shell"
+		}
+		exit, health := fmt.Sprint(e.ExitCode), components.HealthOK
+		if e.Node != "" {
+			labels["node"] = e.Node
+			labels["pod"] = e.Namespace + "/" + e.Pod
+		}
+		switch {
+		case e.ExitCode < 0:
+			exit, health = "-", components.HealthFailing
+			labels["error"] = e.Error
+		case e.ExitCode > 0:
+			health = components.HealthWarning
+		}
+		items = append(items, components.Item{
+			Name:    e.Target(),
+			Labels:  labels,
+			Columns: []string{e.Time.Local().Format("2006-01-02 15:04:05"), e.Context, truncate(command, 40), exit, (time.Duration(e.Duration*1000) * time.Millisecond).String()},
+			Health:  health,
+		})
+	}
+	return tableFromItems(historyHeaders, items)
+}
+
+// LaunchList orders the launches as BuildLaunchList shows them: the
+// favourites, then the recents that are not favourites.
+func LaunchList(launches k8s.Launches) []k8s.Launch {
+	out := append([]k8s.Launch(nil), launches.Favourites...)
+	for _, l := range launches.Recents {
+		if !launches.IsFavourite(l) {
+			out = append(out, l)
+		}
 	}
 	return out
 }
+
+func BuildLaunchList(launches k8s.Launches) list.Model {
+	items := []list.Item{}
+	for _, l := range LaunchList(launches) {
+		kind, health := "recent", components.HealthUnknown
+		if launches.IsFavourite(l) {
+			kind, health = "favourite", components.HealthOK
+		}
+		lastUsed := "never"
+		if !l.LastUsed.IsZero() {
+			lastUsed = age(metav1.NewTime(l.LastUsed)) + " ago"
+		}
+		match := "workload"
+		switch {
+		case l.Selector != "":
+			match = "selector"
+		case l.Workload == "":
+			match = "pod prefix"
+		}
+		items = append(items, components.Item{
+			Name:    l.Target(),
+			Labels:  map[string]string{"context": l.Context, "namespace": l.Namespace, match: strings.TrimSuffix(l.Match(), "*"), "container": l.Container},
+			Columns: []string{l.Context, kind, lastUsed},
+			Health:  health,
+		})
+	}
+	return tableFromItems(launchHeaders, items)
+}
+
+func truncate(s string, width int) string {
+	if r := []rune(s); len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+	return s
+}
+
+func BuildFileList(entries []k8s.FileEntry) list.Model {
+	items := make([]list.Item, len(entries))
+	for i, e := range entries {
+		kind, size := "file", ""
+		switch {
+		case e.Link:
+			kind = "link"
+		case e.Dir:
+			kind = "dir"
+		}
+		if !e.Dir && !e.ModTime.IsZero() {
+			size = format.ByteSize(e.Size)
+		}
+		modified := ""
+		if !e.ModTime.IsZero() {
+			modified = e.ModTime.Local().Format("2006-01-02 15:04")
+		}
+		items[i] = components.Item{Name: e.Name, Columns: []string{kind, size, e.Mode, modified}}
+	}
+	return tableFromItems(fileHeaders, items)
+}
+
+func SelectItem(l *list.Model, name string) {
+	for i, item := range l.VisibleItems() {
+		if item.FilterValue() == name {
+			l.Select(i)
+			return
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package views
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/shlex"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+type broadcastEndedMsg struct {
+	results []k8s.BroadcastResult
+	err     error
+}
+
+// broadcastModel prompts for a command to run in several pods of its
+// parent.
+type broadcastModel struct {
+	input   textinput.Model
+	targets []k8s.BroadcastTarget
+	grouped bool
+	err     error
+	parent  PodsModel
+}
+
+func newBroadcastModel(targets []k8s.BroadcastTarget, parent PodsModel) *broadcastModel {
+	input := textinput.New()
+	input.Prompt = "$ "
+	input.Placeholder = "cat /etc/resolv.conf"
+	input.Focus()
+	return &broadcastModel{input: input, targets: targets, parent: parent}
+}
+
+func (m broadcastModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *broadcastModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch keypress := msg.String(); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return switchTo(m.parent)
+		case "tab":
+			m.grouped = !m.grouped
+			return m, nil
+		case "enter":
+			command, err := shlex.Split(m.input.Value())
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+			if len(command) == 0 {
+				return m, nil
+			}
+			c := &broadcastCommand{
+				client:  m.parent.client,
+				targets: m.targets,
+				options: k8s.BroadcastOptions{
+					Command:     command,
+					Concurrency: k8s.BroadcastConcurrency,
+					Timeout:     k8s.BroadcastTimeout,
+					Grouped:     m.grouped,
+				},
+			}
+			return m.parent, tea.Batch(tea.ClearScreen, execProcess(c, func(err error) tea.Msg {
+				return broadcastEndedMsg{results: c.results, err: err}
+			}))
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *broadcastModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(podBanner))
+	context := utils.ViewContext()
+	pods := make([]string, len(m.targets))
+	for i, t := range m.targets {
+		pods[i] = t.Pod
+	}
+	target := fmt.Sprintf("run in %d pods: %s", len(m.targets), strings.Join(pods, ", "))
+	limits := fmt.Sprintf("%d at a time, %s timeout per pod", k8s.BroadcastConcurrency, k8s.BroadcastTimeout)
+	mode := "output: prefixed with the pod"
+	if m.grouped {
+		mode = "output: grouped by pod"
+	}
+	body := lipgloss.JoinVertical(lipgloss.Left, lipgloss.NewStyle().Width(72).Render(target), limits, "", m.input.View(), "", mode)
+	if m.err != nil {
+		body = lipgloss.JoinVertical(lipgloss.Left, body, styles.FailingStyle.Render(m.err.Error()))
+	}
+	help := styles.HelpStyle.Render("enter run • tab toggle grouping • esc back")
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, styles.PromptStyle.Render(body), help)
+}
+
+// broadcastCommand runs a command in several pods while Bubble Tea has
+// released the terminal, and waits for enter after the summary.
+type broadcastCommand struct {
+	client  k8s.Client
+	targets []k8s.BroadcastTarget
+	options k8s.BroadcastOptions
+	results []k8s.BroadcastResult
+	stdin   io.Reader
+	stdout  io.Writer
+}
+
+func (c *broadcastCommand) SetStdin(r io.Reader)  { c.stdin = r }
+func (c *broadcastCommand) SetStdout(w io.Writer) { c.stdout = w }
+func (c *broadcastCommand) SetStderr(io.Writer)   {}
+
+func (c *broadcastCommand) Run() error {
+	if err := k8s.Confirm(c.stdin, c.stdout, "run the command"); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Running %q in %d pods\n\n", strings.Join(c.options.Command, " "), len(c.targets))
+	c.options.Output = c.stdout
+	c.results = k8s.Broadcast(c.client, c.targets, c.options)
+	fmt.Fprintln(c.stdout)
+	k8s.WriteBroadcastSummary(c.stdout, c.results)
+	fmt.Fprint(c.stdout, "\npress enter to return to ksh")
+	if c.stdin != nil {
+		_, _ = bufio.NewReader(c.stdin).ReadString('\n')
+	}
+	return nil
+}
+
+func broadcastStatus(msg broadcastEndedMsg) string {
+	if msg.err != nil {
+		return styles.StatusStyle.Render("command not run: " + msg.err.Error())
+	}
+	results := msg.results
+	succeeded := 0
+	for _, r := range results {
+		if r.Err == nil {
+			succeeded++
+		}
+	}
+	return styles.StatusStyle.Render(fmt.Sprintf("command succeeded in %d of %d pods", succeeded, len(results)))
+}
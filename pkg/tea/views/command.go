@@ -0,0 +1,79 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/shlex"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+// commandModel prompts for a command to run in the container selected in
+// its parent.
+type commandModel struct {
+	input  textinput.Model
+	tty    bool
+	err    error
+	parent ContainersModel
+}
+
+func newCommandModel(parent ContainersModel) *commandModel {
+	input := textinput.New()
+	input.Prompt = "$ "
+	input.Placeholder = "env"
+	input.Focus()
+	return &commandModel{input: input, parent: parent}
+}
+
+func (m commandModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *commandModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch keypress := msg.String(); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return switchTo(m.parent)
+		case "tab":
+			m.tty = !m.tty
+			return m, nil
+		case "enter":
+			command, err := shlex.Split(m.input.Value())
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+			if len(command) == 0 {
+				return m, nil
+			}
+			p := m.parent
+			return p, tea.Batch(tea.ClearScreen, startSession(p.client, p.namespace, p.pod, p.container, command, m.tty))
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *commandModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(containerBanner))
+	context := utils.ViewContext()
+	target := fmt.Sprintf("run in %s/%s/%s", m.parent.namespace, m.parent.pod, m.parent.container)
+	mode := "tty: off (output is streamed, stdout and stderr separately)"
+	if m.tty {
+		mode = "tty: on (interactive)"
+	}
+	body := lipgloss.JoinVertical(lipgloss.Left, target, "", m.input.View(), "", mode)
+	if m.err != nil {
+		body = lipgloss.JoinVertical(lipgloss.Left, body, styles.FailingStyle.Render(m.err.Error()))
+	}
+	help := styles.HelpStyle.Render("enter run • tab toggle tty • esc back")
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, styles.PromptStyle.Render(body), help)
+}
@@ -0,0 +1,39 @@
+package views
+
+import (
+	"io"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samox73/ksh/pkg/k8s"
+)
+
+// confirmCommand asks whether to go ahead with an action in a protected
+// context while Bubble Tea has released the terminal.
+type confirmCommand struct {
+	action string
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func (c *confirmCommand) SetStdin(r io.Reader)  { c.stdin = r }
+func (c *confirmCommand) SetStdout(w io.Writer) { c.stdout = w }
+func (c *confirmCommand) SetStderr(io.Writer)   {}
+
+func (c *confirmCommand) Run() error {
+	return k8s.Confirm(c.stdin, c.stdout, c.action)
+}
+
+// confirmed runs cmd once the action is confirmed, and otherwise returns the
+// message cancelled makes of the refusal. The terminal is only released if
+// the current context is protected.
+func confirmed(action string, cmd tea.Cmd, cancelled func(err error) tea.Msg) tea.Cmd {
+	if k8s.AssumeYes || !k8s.Protected() {
+		return cmd
+	}
+	return execProcess(&confirmCommand{action: action}, func(err error) tea.Msg {
+		if err != nil {
+			return cancelled(err)
+		}
+		return tea.BatchMsg{cmd}
+	})
+}
@@ -1,6 +1,8 @@
 package views
 
 import (
+	"errors"
+
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -8,7 +10,7 @@ import (
 	"github.com/samox73/ksh/pkg/tea/components"
 	"github.com/samox73/ksh/pkg/tea/styles"
 	"github.com/samox73/ksh/pkg/tea/utils"
-	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/api/core/v1"
 )
 
 const containerBanner = `
@@ -24,14 +26,16 @@ type ContainersModel struct {
 	namespace string
 	pod       string
 	container string
-	clientset kubernetes.Clientset
+	podObject *corev1.Pod
+	status    string
+	client    k8s.Client
 	parent    tea.Model
 }
 
-func (m ContainersModel) GetContainer() string                { return m.container }
-func (m ContainersModel) GetPod() string                      { return m.pod }
-func (m ContainersModel) GetNamespace() string                { return m.namespace }
-func (m ContainersModel) GetClientset() *kubernetes.Clientset { return &m.clientset }
+func (m ContainersModel) GetContainer() string  { return m.container }
+func (m ContainersModel) GetPod() string        { return m.pod }
+func (m ContainersModel) GetNamespace() string  { return m.namespace }
+func (m ContainersModel) GetClient() k8s.Client { return m.client }
 
 func (m ContainersModel) Init() tea.Cmd {
 	return nil
@@ -41,17 +45,80 @@ func (m ContainersModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.items.SetWidth(msg.Width)
-		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(containerBanner), len(m.items.Items())))
+		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(containerBanner), len(m.items.Items())+7))
+		return m, nil
+	case debugStartedMsg:
+		m.status = ""
+		if errors.Is(msg.err, k8s.ErrNotConfirmed) {
+			m.status = debugCancelledStatus(m.pod)
+			return m, nil
+		}
+		if msg.err != nil {
+			retry := func() (tea.Model, tea.Cmd) {
+				m.status = debugStatus(m.pod)
+				return m, startDebugContainer(m.client, m.namespace, m.pod, m.container)
+			}
+			return switchTo(newErrorModel(msg.err, retry, m))
+		}
+		m.container = msg.container
+		return m, startAttach(m.client, m.namespace, m.pod, m.container)
+	case sessionEndedMsg:
+		m.status = sessionStatus(msg)
+		return m, m.refresh()
+	case pinnedMsg:
+		m.status = pinStatus(msg)
 		return m, nil
 	case tea.KeyMsg:
-		switch keypress := msg.String(); keypress {
+		switch keypress := keyOf(msg); keypress {
 		case "q":
-			return m.parent, nil
+			return switchTo(m.parent)
 		case "enter":
 			i, ok := m.items.SelectedItem().(components.Item)
 			m.container = i.Name
 			if ok {
-				return m, tea.Quit
+				if err := k8s.CheckExecutable(m.podObject, m.container); err != nil {
+					retry := func() (tea.Model, tea.Cmd) {
+						return switchTo(buildContainerModel(m.client, m.namespace, m.pod, m.parent))
+					}
+					return switchTo(newErrorModel(err, retry, m))
+				}
+				return m, startSession(m.client, m.namespace, m.pod, m.container, nil, false)
+			}
+		case "x":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				m.container = i.Name
+				if err := k8s.CheckExecutable(m.podObject, m.container); err != nil {
+					return switchTo(newErrorModel(err, nil, m))
+				}
+				return switchTo(newCommandModel(m))
+			}
+		case "D":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				m.container = i.Name
+				m.status = debugStatus(m.pod)
+				return m, startDebugContainer(m.client, m.namespace, m.pod, i.Name)
+			}
+		case "C":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				m.container = i.Name
+				if err := k8s.CheckExecutable(m.podObject, m.container); err != nil {
+					return switchTo(newErrorModel(err, nil, m))
+				}
+				return switchTo(newFilesModel(m.client, m.namespace, m.pod, i.Name, "/", m))
+			}
+		case "L":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				m.container = i.Name
+				return switchTo(newLogsModel(m.client, m.namespace, m.pod, i.Name, m))
+			}
+		case "p":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				return m, pinContainer(m.client, m.podObject, i.Name)
 			}
 		}
 	}
@@ -61,19 +128,39 @@ func (m ContainersModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// refresh reloads the containers of the pod, which may have changed while a
+// session was running, keeping the selection and filter.
+func (m *ContainersModel) refresh() tea.Cmd {
+	pod, err := m.client.Pod(m.namespace, m.pod)
+	if err != nil {
+		return nil
+	}
+	m.podObject = pod
+	selected, _ := m.items.SelectedItem().(components.Item)
+	cmd := utils.SetContainerItems(&m.items, k8s.PodContainers(pod))
+	utils.SelectItem(&m.items, selected.Name)
+	return cmd
+}
+
 func (m ContainersModel) View() string {
 	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(containerBanner))
 	context := utils.ViewContext()
 	items := m.items.View()
-	return lipgloss.JoinVertical(lipgloss.Left, banner, context, items)
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, m.status, items)
 }
 
-func buildContainerModel(namespace string, pod string, parent tea.Model) *ContainersModel {
-	clientset := *k8s.GetKubernetesClientset()
-	containers := k8s.GetContainers(clientset, namespace, pod)
+func buildContainerModel(client k8s.Client, namespace string, pod string, parent tea.Model) tea.Model {
+	retry := func() (tea.Model, tea.Cmd) {
+		return switchTo(buildContainerModel(client, namespace, pod, parent))
+	}
+	p, err := client.Pod(namespace, pod)
+	if err != nil {
+		return newErrorModel(err, retry, parent)
+	}
 	m := &ContainersModel{
-		items:     utils.BuildContainerList(containers),
-		clientset: clientset,
+		items:     utils.BuildContainerList(k8s.PodContainers(p)),
+		podObject: p,
+		client:    client,
 		namespace: namespace,
 		pod:       pod,
 		parent:    parent,
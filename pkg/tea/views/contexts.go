@@ -0,0 +1,99 @@
+package views
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/components"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+const contextBanner = `
+ ██████╗ ██████╗ ███╗   ██╗████████╗███████╗██╗  ██╗████████╗
+██╔════╝██╔═══██╗████╗  ██║╚══██╔══╝██╔════╝╚██╗██╔╝╚══██╔══╝
+██║     ██║   ██║██╔██╗ ██║   ██║   █████╗   ╚███╔╝    ██║   
+██║     ██║   ██║██║╚██╗██║   ██║   ██╔══╝   ██╔██╗    ██║   
+╚██████╗╚██████╔╝██║ ╚████║   ██║   ███████╗██╔╝ ██╗   ██║   
+ ╚═════╝ ╚═════╝ ╚═╝  ╚═══╝   ╚═╝   ╚══════╝╚═╝  ╚═╝   ╚═╝`
+
+type contextsModel struct {
+	items list.Model
+}
+
+func (m contextsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *contextsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	i, _ := m.items.SelectedItem().(components.Item)
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.items.SetWidth(msg.Width)
+		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(contextBanner)-len(i.Labels)-2, len(m.items.Items())+7))
+		return m, nil
+	case tea.KeyMsg:
+		if m.items.FilterState() == list.Filtering {
+			break
+		}
+		switch keypress := keyOf(msg); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "enter":
+			if i, ok := m.items.SelectedItem().(components.Item); ok {
+				return m.useContext(i.Name, false)
+			}
+		case "s":
+			if i, ok := m.items.SelectedItem().(components.Item); ok {
+				return m.useContext(i.Name, true)
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.items, cmd = m.items.Update(msg)
+	return m, cmd
+}
+
+// useContext switches the session to the given context and opens its
+// namespaces. With save set, the context also becomes the current-context of
+// the kubeconfig file.
+func (m *contextsModel) useContext(name string, save bool) (tea.Model, tea.Cmd) {
+	retry := func() (tea.Model, tea.Cmd) { return m.useContext(name, save) }
+	client, err := k8s.UseContext(name)
+	if err != nil {
+		return switchTo(newErrorModel(err, retry, m))
+	}
+	if save {
+		if err := k8s.SaveCurrentContext(name); err != nil {
+			return switchTo(newErrorModel(err, retry, m))
+		}
+	}
+	namespaces := BuildNamespaceModel(client, m)
+	if nm, ok := namespaces.(*namespacesModel); ok {
+		i, _ := m.items.SelectedItem().(components.Item)
+		utils.SelectItem(&nm.items, i.Labels["namespace"])
+	}
+	return switchTo(namespaces)
+}
+
+func (m *contextsModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(contextBanner))
+	context := utils.ViewContext()
+	details := ""
+	if i, ok := m.items.SelectedItem().(components.Item); ok {
+		details = utils.ViewLabels(i.Labels)
+	}
+	items := m.items.View()
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, details, items)
+}
+
+func BuildContextModel() tea.Model {
+	retry := func() (tea.Model, tea.Cmd) { return switchTo(BuildContextModel()) }
+	contexts, err := k8s.GetContexts()
+	if err != nil {
+		return newErrorModel(err, retry, nil)
+	}
+	return &contextsModel{items: utils.BuildContextList(contexts)}
+}
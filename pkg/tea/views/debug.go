@@ -0,0 +1,42 @@
+package views
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/styles"
+)
+
+type debugStartedMsg struct {
+	pod       string
+	container string
+	err       error
+}
+
+// startDebugContainer creates an ephemeral debug container in the pod that
+// shares the process namespace of target, and reports back once it runs.
+// Without a target, the pod's only regular container is used, if any.
+// Protected contexts ask first.
+func startDebugContainer(client k8s.Client, namespace string, pod string, target string) tea.Cmd {
+	create := func() tea.Msg {
+		if target == "" {
+			containers, err := client.Containers(namespace, pod)
+			if err != nil {
+				return debugStartedMsg{pod: pod, err: err}
+			}
+			target, _ = k8s.DefaultContainer(containers)
+		}
+		name, err := client.Debug(namespace, pod, target, k8s.DebugImage)
+		return debugStartedMsg{pod: pod, container: name, err: err}
+	}
+	return confirmed("start a debug container", create, func(err error) tea.Msg {
+		return debugStartedMsg{pod: pod, err: err}
+	})
+}
+
+func debugStatus(pod string) string {
+	return styles.StatusStyle.Render("starting " + k8s.DebugImage + " debug container in " + pod + "...")
+}
+
+func debugCancelledStatus(pod string) string {
+	return styles.StatusStyle.Render("debug container in " + pod + " cancelled")
+}
@@ -0,0 +1,75 @@
+package views
+
+import (
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+const errorBanner = `
+███████╗██████╗ ██████╗  ██████╗ ██████╗ 
+██╔════╝██╔══██╗██╔══██╗██╔═══██╗██╔══██╗
+█████╗  ██████╔╝██████╔╝██║   ██║██████╔╝
+██╔══╝  ██╔══██╗██╔══██╗██║   ██║██╔══██╗
+███████╗██║  ██║██║  ██║╚██████╔╝██║  ██║
+╚══════╝╚═╝  ╚═╝╚═╝  ╚═╝ ╚═════╝ ╚═╝  ╚═╝`
+
+type errorModel struct {
+	err    error
+	retry  func() (tea.Model, tea.Cmd)
+	parent tea.Model
+}
+
+func newErrorModel(err error, retry func() (tea.Model, tea.Cmd), parent tea.Model) *errorModel {
+	return &errorModel{err: err, retry: retry, parent: parent}
+}
+
+func (m errorModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *errorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch keypress := keyOf(msg); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "r":
+			if m.retry != nil {
+				return m.retry()
+			}
+		case "q", "esc":
+			if m.parent == nil {
+				return m, tea.Quit
+			}
+			return switchTo(m.parent)
+		}
+	}
+	return m, nil
+}
+
+func (m *errorModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(errorBanner))
+	context := utils.ViewContext()
+	title := "error"
+	var e *k8s.Error
+	if errors.As(m.err, &e) {
+		title = e.Kind.String()
+	}
+	message := styles.ErrorStyle.Render(title + "\n\n" + m.err.Error())
+	help := []string{}
+	if m.retry != nil {
+		help = append(help, "r retry")
+	}
+	if m.parent != nil {
+		help = append(help, "q back")
+	} else {
+		help = append(help, "q quit")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, message, styles.HelpStyle.Render(strings.Join(help, " • ")))
+}
@@ -0,0 +1,325 @@
+package views
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/format"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/components"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+const filesBanner = `
+███████╗██╗██╗     ███████╗███████╗
+██╔════╝██║██║     ██╔════╝██╔════╝
+█████╗  ██║██║     █████╗  ███████╗
+██╔══╝  ██║██║     ██╔══╝  ╚════██║
+██║     ██║███████╗███████╗███████║
+╚═╝     ╚═╝╚══════╝╚══════╝╚══════╝`
+
+// copyJob is a copy running in the background.
+type copyJob struct {
+	description string
+	upload      bool
+	copied      atomic.Int64
+	total       atomic.Int64
+	done        chan struct{}
+	err         error
+}
+
+type copyTickMsg struct {
+	job *copyJob
+}
+
+func startCopy(client k8s.Client, c k8s.Copy, upload bool, local string, remote string) (*copyJob, tea.Cmd) {
+	job := &copyJob{upload: upload, done: make(chan struct{})}
+	job.total.Store(-1)
+	c.Progress = func(copied int64, total int64) {
+		job.copied.Store(copied)
+		job.total.Store(total)
+	}
+	if upload {
+		job.description = fmt.Sprintf("uploading %s to %s", local, remote)
+	} else {
+		job.description = fmt.Sprintf("downloading %s to %s", remote, local)
+	}
+	run := func() tea.Msg {
+		go func() {
+			if upload {
+				job.err = c.Upload(client, local, remote)
+			} else {
+				job.err = c.Download(client, remote, local)
+			}
+			close(job.done)
+		}()
+		return copyTickMsg{job: job}
+	}
+	if !upload {
+		return job, run
+	}
+	// uploads change the container, so protected contexts ask first
+	return job, confirmed("upload the files", run, func(err error) tea.Msg {
+		job.err = err
+		close(job.done)
+		return copyTickMsg{job: job}
+	})
+}
+
+func tickCopy(job *copyJob) tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg { return copyTickMsg{job: job} })
+}
+
+// filesModel browses the filesystem of a container, shows its text files
+// and copies files from and to it.
+type filesModel struct {
+	items     list.Model
+	entries   map[string]k8s.FileEntry
+	client    k8s.Client
+	namespace string
+	pod       string
+	container string
+	dir       string
+	input     textinput.Model
+	upload    bool
+	job       *copyJob
+	progress  progress.Model
+	status    string
+	width     int
+	height    int
+	parent    tea.Model
+}
+
+func newFilesModel(client k8s.Client, namespace string, pod string, container string, dir string, parent tea.Model) tea.Model {
+	m := &filesModel{
+		client:    client,
+		namespace: namespace,
+		pod:       pod,
+		container: container,
+		input:     textinput.New(),
+		progress:  progress.New(progress.WithDefaultGradient(), progress.WithWidth(40)),
+		parent:    parent,
+	}
+	if err := m.open(dir); err != nil {
+		retry := func() (tea.Model, tea.Cmd) {
+			return switchTo(newFilesModel(client, namespace, pod, container, dir, parent))
+		}
+		return newErrorModel(err, retry, parent)
+	}
+	return m
+}
+
+// open lists dir and shows it.
+func (m *filesModel) open(dir string) error {
+	entries, err := k8s.ListDir(m.client, m.namespace, m.pod, m.container, dir)
+	if err != nil {
+		return err
+	}
+	m.dir = dir
+	m.entries = map[string]k8s.FileEntry{}
+	for _, e := range entries {
+		m.entries[e.Name] = e
+	}
+	m.items = utils.BuildFileList(entries)
+	m.items.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open")),
+			key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "up")),
+			key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "download")),
+			key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "upload")),
+		}
+	}
+	m.resize()
+	return nil
+}
+
+func (m *filesModel) resize() {
+	if m.width == 0 {
+		return
+	}
+	m.items.SetWidth(m.width)
+	m.items.SetHeight(utils.MinInt(m.height-lipgloss.Height(filesBanner)-5, len(m.items.Items())+7))
+}
+
+func (m filesModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *filesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+		return m, nil
+	case copyTickMsg:
+		if msg.job != m.job {
+			return m, nil
+		}
+		select {
+		case <-m.job.done:
+		default:
+			return m, tickCopy(m.job)
+		}
+		job := m.job
+		m.job = nil
+		if errors.Is(job.err, k8s.ErrNotConfirmed) {
+			m.status = styles.StatusStyle.Render("upload cancelled")
+			return m, nil
+		}
+		if job.err != nil {
+			m.status = ""
+			return switchTo(newErrorModel(job.err, nil, m))
+		}
+		m.status = styles.StatusStyle.Render(fmt.Sprintf("done %s (%s)", job.description, format.ByteSize(job.copied.Load())))
+		if job.upload {
+			m.reload()
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			return m.updateInput(msg)
+		}
+		if m.items.FilterState() == list.Filtering {
+			break
+		}
+		switch keypress := keyOf(msg); keypress {
+		case "q":
+			return switchTo(m.parent)
+		case "enter":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok {
+				return m.openEntry(m.entries[i.Name])
+			}
+		case "backspace", "-":
+			if m.dir != "/" {
+				return m.cd(path.Dir(m.dir))
+			}
+		case "s":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.job == nil {
+				m.upload = false
+				return m, m.prompt("save to: ", "./"+i.Name)
+			}
+		case "p":
+			if m.job == nil {
+				m.upload = true
+				return m, m.prompt("upload: ", "")
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.items, cmd = m.items.Update(msg)
+	return m, cmd
+}
+
+// openEntry opens directories, and links to them, in the list and files in
+// the pager.
+func (m *filesModel) openEntry(e k8s.FileEntry) (tea.Model, tea.Cmd) {
+	p := path.Join(m.dir, e.Name)
+	if e.Dir || (e.Link && k8s.IsDir(m.client, m.namespace, m.pod, m.container, p)) {
+		return m.cd(p)
+	}
+	content, truncated, err := k8s.ReadFile(m.client, m.namespace, m.pod, m.container, p, pagerLimit)
+	if err != nil {
+		m.status = styles.StatusStyle.Render(err.Error())
+		return m, nil
+	}
+	if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		m.status = styles.StatusStyle.Render(e.Name + " is a binary file, press s to download it")
+		return m, nil
+	}
+	title := fmt.Sprintf("%s/%s/%s:%s", m.namespace, m.pod, m.container, p)
+	if truncated {
+		title += fmt.Sprintf(" (first %s)", format.ByteSize(pagerLimit))
+	}
+	m.status = ""
+	return switchTo(newPagerModel(title, string(content), m))
+}
+
+func (m *filesModel) cd(dir string) (tea.Model, tea.Cmd) {
+	previous := path.Base(m.dir)
+	if err := m.open(dir); err != nil {
+		m.status = styles.StatusStyle.Render(err.Error())
+		return m, nil
+	}
+	m.status = ""
+	utils.SelectItem(&m.items, previous)
+	return m, tea.ClearScreen
+}
+
+func (m *filesModel) reload() {
+	selected, _ := m.items.SelectedItem().(components.Item)
+	if err := m.open(m.dir); err == nil {
+		utils.SelectItem(&m.items, selected.Name)
+	}
+}
+
+func (m *filesModel) prompt(label string, value string) tea.Cmd {
+	m.input.Prompt = label
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	return m.input.Focus()
+}
+
+func (m *filesModel) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		local := m.input.Value()
+		if local == "" {
+			return m, nil
+		}
+		m.input.Blur()
+		c := k8s.Copy{Namespace: m.namespace, Pod: m.pod, Container: m.container}
+		remote := m.dir
+		if !m.upload {
+			i, _ := m.items.SelectedItem().(components.Item)
+			remote = path.Join(m.dir, i.Name)
+		}
+		var cmd tea.Cmd
+		m.job, cmd = startCopy(m.client, c, m.upload, local, remote)
+		return m, cmd
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *filesModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(filesBanner))
+	context := utils.ViewContext()
+	location := styles.HeaderStyle.Render(fmt.Sprintf("%s/%s/%s:%s", m.namespace, m.pod, m.container, m.dir))
+	status := m.status
+	if m.job != nil {
+		status = styles.StatusStyle.Render(m.job.description + " " + m.jobProgress())
+	}
+	if m.input.Focused() {
+		help := styles.HelpStyle.Render("enter copy • esc cancel")
+		return lipgloss.JoinVertical(lipgloss.Left, banner, context, location, status, styles.PromptStyle.Render(m.input.View()), help)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, location, status, m.items.View())
+}
+
+func (m *filesModel) jobProgress() string {
+	copied, total := m.job.copied.Load(), m.job.total.Load()
+	if total <= 0 {
+		return format.ByteSize(copied)
+	}
+	return m.progress.ViewAs(min(1, float64(copied)/float64(total))) + " " + format.ByteSize(copied) + " / " + format.ByteSize(total)
+}
@@ -0,0 +1,255 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/components"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+const portBanner = `
+██████╗  ██████╗ ██████╗ ████████╗███████╗
+██╔══██╗██╔═══██╗██╔══██╗╚══██╔══╝██╔════╝
+██████╔╝██║   ██║██████╔╝   ██║   ███████╗
+██╔═══╝ ██║   ██║██╔══██╗   ██║   ╚════██║
+██║     ╚██████╔╝██║  ██║   ██║   ███████║
+╚═╝      ╚═════╝ ╚═╝  ╚═╝   ╚═╝   ╚══════╝`
+
+// forwards are the port forwards started from the TUI. They keep running in
+// the background while other views are open.
+var forwards []*k8s.Forward
+
+type forwardStartedMsg struct {
+	forward *k8s.Forward
+	err     error
+}
+
+type forwardTickMsg struct {
+	model *forwardsModel
+}
+
+func startForward(client k8s.Client, namespace string, pod string, localPort int, remotePort int) tea.Cmd {
+	forward := func() tea.Msg {
+		f, err := client.PortForward(namespace, pod, localPort, remotePort)
+		return forwardStartedMsg{forward: f, err: err}
+	}
+	return confirmed("forward the port", forward, func(err error) tea.Msg {
+		return forwardStartedMsg{err: err}
+	})
+}
+
+// portsModel lists the ports of a pod and prompts for the local port to
+// forward the selected one to.
+type portsModel struct {
+	items     list.Model
+	input     textinput.Model
+	namespace string
+	pod       string
+	port      int
+	client    k8s.Client
+	status    string
+	parent    tea.Model
+}
+
+func newPortsModel(client k8s.Client, namespace string, pod string, parent tea.Model) tea.Model {
+	retry := func() (tea.Model, tea.Cmd) { return switchTo(newPortsModel(client, namespace, pod, parent)) }
+	p, err := client.Pod(namespace, pod)
+	if err != nil {
+		return newErrorModel(err, retry, parent)
+	}
+	input := textinput.New()
+	input.Prompt = "local port: "
+	m := &portsModel{
+		items:     utils.BuildPortList(p),
+		input:     input,
+		namespace: namespace,
+		pod:       pod,
+		client:    client,
+		parent:    parent,
+	}
+	if len(m.items.Items()) == 0 {
+		m.status = styles.StatusStyle.Render("pod " + pod + " declares no container ports")
+	}
+	return m
+}
+
+func (m portsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *portsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.items.SetWidth(msg.Width)
+		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(portBanner)-4, len(m.items.Items())+7))
+		return m, nil
+	case forwardStartedMsg:
+		m.status = ""
+		if errors.Is(msg.err, k8s.ErrNotConfirmed) {
+			m.status = styles.StatusStyle.Render("port forward cancelled")
+			return m, nil
+		}
+		if msg.err != nil {
+			return switchTo(newErrorModel(msg.err, nil, m))
+		}
+		forwards = append(forwards, msg.forward)
+		return switchTo(newForwardsModel(m.parent))
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			return m.updateInput(msg)
+		}
+		switch keypress := keyOf(msg); keypress {
+		case "q":
+			return switchTo(m.parent)
+		case "enter":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if !ok {
+				break
+			}
+			if i.Columns[1] != "TCP" {
+				m.status = styles.StatusStyle.Render("only TCP ports can be forwarded")
+				return m, nil
+			}
+			m.port, _ = strconv.Atoi(i.Name)
+			m.input.SetValue(i.Name)
+			m.input.CursorEnd()
+			return m, m.input.Focus()
+		case "P":
+			if m.items.FilterState() != list.Filtering {
+				return switchTo(newForwardsModel(m))
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.items, cmd = m.items.Update(msg)
+	return m, cmd
+}
+
+func (m *portsModel) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		local, err := strconv.Atoi(m.input.Value())
+		if err != nil || local < 0 || local > 65535 {
+			m.status = styles.StatusStyle.Render("invalid local port " + strconv.Quote(m.input.Value()))
+			return m, nil
+		}
+		m.input.Blur()
+		m.status = styles.StatusStyle.Render(fmt.Sprintf("forwarding localhost:%d to %s:%d", local, m.pod, m.port))
+		return m, startForward(m.client, m.namespace, m.pod, local, m.port)
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *portsModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(portBanner))
+	context := utils.ViewContext()
+	items := m.items.View()
+	if m.input.Focused() {
+		prompt := lipgloss.JoinVertical(lipgloss.Left,
+			fmt.Sprintf("forward %s/%s:%d", m.namespace, m.pod, m.port), "", m.input.View(), "", "0 picks a free port")
+		help := styles.HelpStyle.Render("enter forward • esc cancel")
+		return lipgloss.JoinVertical(lipgloss.Left, banner, context, m.status, styles.PromptStyle.Render(prompt), help)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, m.status, items)
+}
+
+// forwardsModel lists the port forwards and lets the user stop them.
+type forwardsModel struct {
+	items  list.Model
+	status string
+	parent tea.Model
+}
+
+func newForwardsModel(parent tea.Model) *forwardsModel {
+	items := utils.BuildForwardList(forwards)
+	items.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{key.NewBinding(key.WithKeys("X"), key.WithHelp("X", "stop"))}
+	}
+	return &forwardsModel{items: items, parent: parent}
+}
+
+func (m *forwardsModel) Init() tea.Cmd {
+	return m.tick()
+}
+
+// tick refreshes the list every second to update the transferred bytes.
+func (m *forwardsModel) tick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return forwardTickMsg{model: m} })
+}
+
+func (m *forwardsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.items.SetWidth(msg.Width)
+		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(portBanner)-4, len(m.items.Items())+7))
+		return m, nil
+	case forwardTickMsg:
+		if msg.model != m {
+			return m, nil
+		}
+		return m, tea.Batch(m.refresh(), m.tick())
+	case tea.KeyMsg:
+		switch keypress := keyOf(msg); keypress {
+		case "q":
+			return switchTo(m.parent)
+		case "X":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				m.stop(i.Name)
+				return m, m.refresh()
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.items, cmd = m.items.Update(msg)
+	return m, cmd
+}
+
+// stop stops the forward listening on the local address and removes it
+// from the list.
+func (m *forwardsModel) stop(local string) {
+	for i, f := range forwards {
+		if fmt.Sprintf("localhost:%d", f.LocalPort) == local {
+			f.Stop()
+			forwards = append(forwards[:i:i], forwards[i+1:]...)
+			m.status = styles.StatusStyle.Render("stopped " + f.String())
+			return
+		}
+	}
+}
+
+func (m *forwardsModel) refresh() tea.Cmd {
+	selected, _ := m.items.SelectedItem().(components.Item)
+	cmd := utils.SetForwardItems(&m.items, forwards)
+	utils.SelectItem(&m.items, selected.Name)
+	return cmd
+}
+
+func (m *forwardsModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(portBanner))
+	context := utils.ViewContext()
+	status := m.status
+	if len(forwards) == 0 {
+		status = styles.StatusStyle.Render("no port forwards, press F on a pod to start one")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, status, m.items.View())
+}
@@ -0,0 +1,145 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/components"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+const historyBanner = `
+██╗  ██╗██╗███████╗████████╗ ██████╗ ██████╗ ██╗   ██╗
+██║  ██║██║██╔════╝╚══██╔══╝██╔═══██╗██╔══██╗╚██╗ ██╔╝
+███████║██║███████╗   ██║   ██║   ██║██████╔╝ ╚████╔╝ 
+██╔══██║██║╚════██║   ██║   ██║   ██║██╔══██╗  ╚██╔╝  
+██║  ██║██║███████║   ██║   ╚██████╔╝██║  ██║   ██║   
+╚═╝  ╚═╝╚═╝╚══════╝   ╚═╝    ╚═════╝ ╚═╝  ╚═╝   ╚═╝   `
+
+// historyModel lists the sessions of the audit log and opens a shell in the
+// target of an entry again.
+type historyModel struct {
+	items  list.Model
+	client k8s.Client
+	filter k8s.AuditFilter
+	limit  int
+	status string
+}
+
+// BuildHistoryModel opens the TUI at the entries of the audit log that
+// match the filter, at most limit of them if it is positive.
+func BuildHistoryModel(client k8s.Client, filter k8s.AuditFilter, limit int) tea.Model {
+	retry := func() (tea.Model, tea.Cmd) { return switchTo(BuildHistoryModel(client, filter, limit)) }
+	m := &historyModel{client: client, filter: filter, limit: limit}
+	if err := m.load(); err != nil {
+		return newErrorModel(err, retry, nil)
+	}
+	return m
+}
+
+func (m *historyModel) load() error {
+	entries, err := k8s.ReadAuditLog(m.filter)
+	if err != nil {
+		return err
+	}
+	if m.limit > 0 && len(entries) > m.limit {
+		entries = entries[len(entries)-m.limit:]
+	}
+	width, height := m.items.Width(), m.items.Height()
+	m.items = utils.BuildHistoryList(entries)
+	m.items.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "reconnect"))}
+	}
+	if width > 0 {
+		m.items.SetSize(width, height)
+	}
+	return nil
+}
+
+func (m historyModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *historyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	i, _ := m.items.SelectedItem().(components.Item)
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.items.SetWidth(msg.Width)
+		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(historyBanner)-len(i.Labels)-5, len(m.items.Items())+7))
+		return m, nil
+	case sessionEndedMsg:
+		m.status = sessionStatus(msg)
+		if err := m.load(); err != nil {
+			m.status = styles.StatusStyle.Render(err.Error())
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if m.items.FilterState() == list.Filtering {
+			break
+		}
+		switch keypress := keyOf(msg); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "enter":
+			if i, ok := m.items.SelectedItem().(components.Item); ok {
+				return m.reconnect(i)
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.items, cmd = m.items.Update(msg)
+	return m, cmd
+}
+
+// reconnect opens a shell in the container of the entry, switching to its
+// context first, or a new shell on its node. Containers that no longer run
+// are reported in the status bar.
+func (m *historyModel) reconnect(i components.Item) (tea.Model, tea.Cmd) {
+	if current, _ := k8s.CurrentContext(); i.Labels["context"] != current {
+		client, err := k8s.UseContext(i.Labels["context"])
+		if err != nil {
+			m.status = styles.StatusStyle.Render(err.Error())
+			return m, nil
+		}
+		m.client = client
+	}
+	if node := i.Labels["node"]; node != "" {
+		return m, startNodeShell(m.client, node)
+	}
+	parts := strings.SplitN(i.Name, "/", 3)
+	if len(parts) != 3 {
+		return m, nil
+	}
+	namespace, podName, container := parts[0], parts[1], parts[2]
+	pod, err := m.client.Pod(namespace, podName)
+	if err == nil {
+		err = k8s.CheckExecutable(pod, container)
+	}
+	if errors.Is(err, k8s.ErrNotFound) {
+		m.status = styles.StatusStyle.Render(fmt.Sprintf("%s no longer exists", i.Name))
+		return m, nil
+	}
+	if err != nil {
+		m.status = styles.StatusStyle.Render(err.Error())
+		return m, nil
+	}
+	return m, startSession(m.client, namespace, podName, container, nil, false)
+}
+
+func (m *historyModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(historyBanner))
+	context := utils.ViewContext()
+	details := ""
+	if i, ok := m.items.SelectedItem().(components.Item); ok {
+		details = utils.ViewLabels(i.Labels)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, m.status, details, m.items.View())
+}
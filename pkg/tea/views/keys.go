@@ -0,0 +1,26 @@
+package views
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ActionKeys are the keys the views act on, besides the navigation and
+// filtering of the lists.
+var ActionKeys = []string{
+	"enter", "esc", "backspace", "tab", " ", "-", "q",
+	"1", "2", "3", "4", "5", "6", "7", "8", "9",
+	"A", "C", "D", "F", "G", "L", "N", "P", "R", "S", "T", "X",
+	"f", "g", "n", "p", "r", "s", "t", "w", "x",
+}
+
+// KeyAliases binds additional keys to the actions of ActionKeys, e.g.
+// "ctrl+d" to "D". A key bound this way no longer does what it did before.
+var KeyAliases = map[string]string{}
+
+// keyOf returns the key the views act on for a key press.
+func keyOf(msg tea.KeyMsg) string {
+	if key, ok := KeyAliases[msg.String()]; ok {
+		return key
+	}
+	return msg.String()
+}
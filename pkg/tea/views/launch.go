@@ -0,0 +1,291 @@
+package views
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/components"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const launchBanner = `
+██╗      █████╗ ██╗   ██╗███╗   ██╗ ██████╗██╗  ██╗
+██║     ██╔══██╗██║   ██║████╗  ██║██╔════╝██║  ██║
+██║     ███████║██║   ██║██╔██╗ ██║██║     ███████║
+██║     ██╔══██║██║   ██║██║╚██╗██║██║     ██╔══██║
+███████╗██║  ██║╚██████╔╝██║ ╚████║╚██████╗██║  ██║
+╚══════╝╚═╝  ╚═╝ ╚═════╝ ╚═╝  ╚═══╝ ╚═════╝╚═╝  ╚═╝`
+
+// launchModel lists the favourite and recently used containers and opens a
+// shell in the current pod of one of them.
+type launchModel struct {
+	items    list.Model
+	client   k8s.Client
+	launches k8s.Launches
+	input    textinput.Model
+	status   string
+}
+
+// BuildStartModel opens the TUI at the favourite and recently used
+// containers, or at the namespace list if there are none.
+func BuildStartModel(client k8s.Client) tea.Model {
+	m := buildLaunchModel(client)
+	if l, ok := m.(*launchModel); ok && len(l.items.Items()) == 0 {
+		return BuildNamespaceModel(client, BuildContextModel())
+	}
+	return m
+}
+
+func buildLaunchModel(client k8s.Client) tea.Model {
+	retry := func() (tea.Model, tea.Cmd) { return switchTo(buildLaunchModel(client)) }
+	m := &launchModel{client: client, input: textinput.New()}
+	if err := m.load(); err != nil {
+		return newErrorModel(err, retry, nil)
+	}
+	return m
+}
+
+func (m *launchModel) load() error {
+	launches, err := k8s.LoadLaunches()
+	if err != nil {
+		return err
+	}
+	m.setLaunches(launches)
+	return nil
+}
+
+func (m *launchModel) setLaunches(launches k8s.Launches) {
+	selected, _ := m.items.SelectedItem().(components.Item)
+	width, height := m.items.Width(), m.items.Height()
+	m.launches = launches
+	m.items = utils.BuildLaunchList(launches)
+	m.items.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter/1-9", "open")),
+			key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pin")),
+			key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "namespaces")),
+		}
+	}
+	if width > 0 {
+		m.items.SetSize(width, height)
+	}
+	utils.SelectItem(&m.items, selected.Name)
+}
+
+// save persists the launches and shows them.
+func (m *launchModel) save(launches k8s.Launches) {
+	if err := launches.Save(); err != nil {
+		m.status = styles.StatusStyle.Render(err.Error())
+		return
+	}
+	m.setLaunches(launches)
+}
+
+// selected returns the launch of the selected item.
+func (m *launchModel) selected() (k8s.Launch, bool) {
+	i, ok := m.items.SelectedItem().(components.Item)
+	if !ok {
+		return k8s.Launch{}, false
+	}
+	for _, l := range utils.LaunchList(m.launches) {
+		if l.Target() == i.Name && l.Context == i.Labels["context"] {
+			return l, true
+		}
+	}
+	return k8s.Launch{}, false
+}
+
+func (m launchModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *launchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	i, _ := m.items.SelectedItem().(components.Item)
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.items.SetWidth(msg.Width)
+		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(launchBanner)-len(i.Labels)-5, len(m.items.Items())+7))
+		return m, nil
+	case sessionEndedMsg:
+		m.status = sessionStatus(msg)
+		if err := m.load(); err != nil {
+			m.status = styles.StatusStyle.Render(err.Error())
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			return m.updateInput(msg)
+		}
+		if m.items.FilterState() == list.Filtering {
+			break
+		}
+		l, ok := m.selected()
+		switch keypress := keyOf(msg); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "n":
+			return switchTo(BuildNamespaceModel(m.client, BuildContextModel()))
+		case "enter":
+			if ok {
+				return m.open(l)
+			}
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			n, _ := strconv.Atoi(keypress)
+			if visible := m.items.VisibleItems(); n <= len(visible) {
+				m.items.Select(n - 1)
+				if l, ok := m.selected(); ok {
+					return m.open(l)
+				}
+			}
+		case "p":
+			if ok {
+				launches := m.launches
+				if launches.IsFavourite(l) {
+					launches.Unpin(l)
+				} else {
+					launches.Pin(l)
+				}
+				m.save(launches)
+			}
+			return m, nil
+		case "X":
+			if ok {
+				launches := m.launches
+				launches.Remove(l)
+				m.save(launches)
+			}
+			return m, nil
+		case "S":
+			if ok {
+				m.input.Prompt = "selector: "
+				m.input.SetValue(m.selectorOf(l))
+				m.input.CursorEnd()
+				return m, m.input.Focus()
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.items, cmd = m.items.Update(msg)
+	return m, cmd
+}
+
+// selectorOf returns the label selector of the launch, or of its workload
+// if that can be looked up in the current context.
+func (m *launchModel) selectorOf(l k8s.Launch) string {
+	if current, _ := k8s.CurrentContext(); l.Selector != "" || l.Workload == "" || l.Context != current {
+		return l.Selector
+	}
+	workloads, err := m.client.Workloads(l.Namespace)
+	if err != nil {
+		return ""
+	}
+	for _, w := range workloads {
+		if w.Kind.String()+"/"+w.Name == l.Workload {
+			return w.Selector
+		}
+	}
+	return ""
+}
+
+// updateInput matches the selected launch by the entered label selector
+// instead, and pins it.
+func (m *launchModel) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		selector, err := k8s.ParseSelector(m.input.Value())
+		if err == nil && selector.String() == "" {
+			err = fmt.Errorf("the selector is empty")
+		}
+		if err != nil {
+			m.status = styles.StatusStyle.Render(err.Error())
+			return m, nil
+		}
+		m.input.Blur()
+		if l, ok := m.selected(); ok {
+			launches := m.launches
+			launches.Unpin(l)
+			l.Workload, l.Selector, l.PodPrefix = "", selector.String(), ""
+			launches.Pin(l)
+			m.save(launches)
+			utils.SelectItem(&m.items, l.Target())
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// open switches to the context of the launch, finds its current pod and
+// opens a shell in it.
+func (m *launchModel) open(l k8s.Launch) (tea.Model, tea.Cmd) {
+	if current, _ := k8s.CurrentContext(); l.Context != current {
+		client, err := k8s.UseContext(l.Context)
+		if err != nil {
+			m.status = styles.StatusStyle.Render(err.Error())
+			return m, nil
+		}
+		m.client = client
+	}
+	pod, err := k8s.ResolveLaunch(m.client, l)
+	if err != nil {
+		m.status = styles.StatusStyle.Render(err.Error())
+		return m, nil
+	}
+	return m, startSession(m.client, l.Namespace, pod, l.Container, nil, false)
+}
+
+type pinnedMsg struct {
+	launch k8s.Launch
+	err    error
+}
+
+// pinContainer pins the container of the pod by the workload owning it,
+// which takes a few lookups, and reports back.
+func pinContainer(client k8s.Client, pod *corev1.Pod, container string) tea.Cmd {
+	return func() tea.Msg {
+		l := k8s.NewLaunch(client, pod, container)
+		launches, err := k8s.LoadLaunches()
+		if err == nil {
+			launches.Pin(l)
+			err = launches.Save()
+		}
+		return pinnedMsg{launch: l, err: err}
+	}
+}
+
+func pinStatus(msg pinnedMsg) string {
+	if msg.err != nil {
+		return styles.StatusStyle.Render(msg.err.Error())
+	}
+	return styles.StatusStyle.Render("pinned " + msg.launch.Target())
+}
+
+func (m *launchModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(launchBanner))
+	context := utils.ViewContext()
+	details := ""
+	if i, ok := m.items.SelectedItem().(components.Item); ok {
+		details = utils.ViewLabels(i.Labels)
+	}
+	items := m.items.View()
+	if m.input.Focused() {
+		items = lipgloss.JoinVertical(lipgloss.Left, styles.PromptStyle.Render(m.input.View()),
+			styles.HelpStyle.Render("e.g. app=api,tier!=cache • enter pin • esc cancel"))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, m.status, details, items)
+}
@@ -0,0 +1,462 @@
+package views
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+const logBanner = `
+██╗      ██████╗  ██████╗ ███████╗
+██║     ██╔═══██╗██╔════╝ ██╔════╝
+██║     ██║   ██║██║  ███╗███████╗
+██║     ██║   ██║██║   ██║╚════██║
+███████╗╚██████╔╝╚██████╔╝███████║
+╚══════╝ ╚═════╝  ╚═════╝ ╚══════╝`
+
+// maxLogLines is how many lines the log view keeps; older lines are dropped.
+const maxLogLines = 10000
+
+// logBatchSize is how many lines are read from a stream before the view is
+// updated.
+const logBatchSize = 500
+
+var (
+	logSinceSteps = []time.Duration{0, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+	logTailSteps  = []int64{100, 1000, maxLogLines, 0}
+)
+
+// logStream reads the lines of a log stream in the background.
+type logStream struct {
+	body  io.ReadCloser
+	lines chan string
+	done  chan struct{}
+	once  sync.Once
+	err   error
+}
+
+func newLogStream(body io.ReadCloser) *logStream {
+	s := &logStream{body: body, lines: make(chan string, logBatchSize), done: make(chan struct{})}
+	go s.read()
+	return s
+}
+
+func (s *logStream) read() {
+	defer close(s.lines)
+	scanner := bufio.NewScanner(s.body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		select {
+		case s.lines <- scanner.Text():
+		case <-s.done:
+			return
+		}
+	}
+	s.err = scanner.Err()
+}
+
+func (s *logStream) Stop() {
+	s.once.Do(func() {
+		close(s.done)
+		s.body.Close()
+	})
+}
+
+type logsOpenedMsg struct {
+	stream *logStream
+	err    error
+}
+
+type logLinesMsg struct {
+	stream *logStream
+	lines  []string
+	ended  bool
+	err    error
+}
+
+func openLogs(client k8s.Client, namespace string, pod string, options k8s.LogOptions) tea.Cmd {
+	return func() tea.Msg {
+		body, err := client.Logs(namespace, pod, options)
+		if err != nil {
+			return logsOpenedMsg{err: err}
+		}
+		return logsOpenedMsg{stream: newLogStream(body)}
+	}
+}
+
+// waitForLogLines waits for the next line of the stream and returns it
+// together with the lines that are already buffered.
+func waitForLogLines(s *logStream) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-s.lines
+		if !ok {
+			return logLinesMsg{stream: s, ended: true, err: s.err}
+		}
+		lines := []string{line}
+		for len(lines) < logBatchSize {
+			select {
+			case line, ok := <-s.lines:
+				if !ok {
+					return logLinesMsg{stream: s, lines: lines, ended: true, err: s.err}
+				}
+				lines = append(lines, line)
+			default:
+				return logLinesMsg{stream: s, lines: lines}
+			}
+		}
+		return logLinesMsg{stream: s, lines: lines}
+	}
+}
+
+// logsModel streams the logs of a container.
+type logsModel struct {
+	client    k8s.Client
+	namespace string
+	pod       string
+	options   k8s.LogOptions
+	stream    *logStream
+	lines     []string
+	// shown holds the lines in the viewport, rendered the same lines as they
+	// are drawn and offsets the row of the viewport each of them starts at.
+	shown    []string
+	rendered []string
+	offsets  []int
+	// pending counts the lines received while the view is paused.
+	pending int
+	view    viewport.Model
+	search  textinput.Model
+	query   *regexp.Regexp
+	paused  bool
+	wrap    bool
+	status  string
+	notice  string
+	parent  tea.Model
+}
+
+func newLogsModel(client k8s.Client, namespace string, pod string, container string, parent tea.Model) *logsModel {
+	width, height := terminalSize()
+	view := viewport.New(width, logViewHeight(height))
+	view.KeyMap.PageDown = key.NewBinding(key.WithKeys("pgdown"))
+	search := textinput.New()
+	search.Prompt = "/"
+	return &logsModel{
+		client:    client,
+		namespace: namespace,
+		pod:       pod,
+		options:   k8s.LogOptions{Container: container, Follow: true, TailLines: logTailSteps[1]},
+		view:      view,
+		search:    search,
+		parent:    parent,
+	}
+}
+
+// logViewHeight is the height left for the logs below the banner and above
+// the help.
+func logViewHeight(height int) int {
+	return max(height-lipgloss.Height(logBanner)-6, 3)
+}
+
+func (m *logsModel) Init() tea.Cmd {
+	return m.restart()
+}
+
+// restart drops the shown lines and opens a new stream with the current
+// options.
+func (m *logsModel) restart() tea.Cmd {
+	if m.stream != nil {
+		m.stream.Stop()
+		m.stream = nil
+	}
+	m.lines, m.shown, m.rendered, m.offsets = nil, nil, nil, nil
+	m.pending = 0
+	m.view.SetContent("")
+	m.status = "connecting"
+	return openLogs(m.client, m.namespace, m.pod, m.options)
+}
+
+func (m *logsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.view.Width = msg.Width
+		m.view.Height = logViewHeight(msg.Height)
+		m.render()
+		return m, nil
+	case logsOpenedMsg:
+		if msg.err != nil {
+			m.status = styles.FailingStyle.Render(msg.err.Error())
+			return m, nil
+		}
+		m.stream = msg.stream
+		m.status = ""
+		return m, waitForLogLines(m.stream)
+	case logLinesMsg:
+		if msg.stream != m.stream {
+			return m, nil
+		}
+		m.append(msg.lines)
+		if msg.ended {
+			m.status = "stream ended"
+			if msg.err != nil {
+				m.status = styles.FailingStyle.Render("stream ended: " + msg.err.Error())
+			}
+			return m, nil
+		}
+		return m, waitForLogLines(m.stream)
+	case tea.KeyMsg:
+		if m.search.Focused() {
+			return m.updateSearch(msg)
+		}
+		m.notice = ""
+		switch keypress := keyOf(msg); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "q":
+			return m.back()
+		case "esc":
+			if m.query != nil {
+				m.query = nil
+				m.render()
+				return m, nil
+			}
+			return m.back()
+		case "/":
+			m.search.SetValue("")
+			return m, m.search.Focus()
+		case "n":
+			m.jump(1, m.view.YOffset)
+			return m, nil
+		case "N":
+			m.jump(-1, m.view.YOffset)
+			return m, nil
+		case " ":
+			m.paused = !m.paused
+			if !m.paused {
+				m.pending = 0
+				m.render()
+				m.view.GotoBottom()
+			}
+			return m, nil
+		case "w":
+			m.wrap = !m.wrap
+			m.render()
+			return m, nil
+		case "f":
+			m.options.Follow = !m.options.Follow
+			return m, m.restart()
+		case "p":
+			m.options.Previous = !m.options.Previous
+			return m, m.restart()
+		case "t":
+			m.options.Timestamps = !m.options.Timestamps
+			return m, m.restart()
+		case "s":
+			m.options.Since = nextStep(logSinceSteps, m.options.Since)
+			return m, m.restart()
+		case "T":
+			m.options.TailLines = nextStep(logTailSteps, m.options.TailLines)
+			return m, m.restart()
+		case "g", "home":
+			m.view.GotoTop()
+			return m, nil
+		case "G", "end":
+			m.view.GotoBottom()
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.view, cmd = m.view.Update(msg)
+	return m, cmd
+}
+
+func (m *logsModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.search.Blur()
+		return m, nil
+	case "enter":
+		m.search.Blur()
+		m.query = nil
+		if value := m.search.Value(); value != "" {
+			m.query = regexp.MustCompile("(?i)" + regexp.QuoteMeta(value))
+		}
+		m.render()
+		m.jump(1, m.view.YOffset-1)
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	return m, cmd
+}
+
+func (m *logsModel) back() (tea.Model, tea.Cmd) {
+	if m.stream != nil {
+		m.stream.Stop()
+	}
+	return switchTo(m.parent)
+}
+
+// append adds lines to the buffer and, unless the view is paused, shows
+// them, following the end of the logs if the view was already there.
+func (m *logsModel) append(lines []string) {
+	m.lines = append(m.lines, lines...)
+	if m.paused {
+		m.pending += len(lines)
+	}
+	if len(m.lines) > maxLogLines {
+		// Drop a tenth at once, so that the lines are not rendered again
+		// for every batch once the buffer is full.
+		m.lines = m.lines[len(m.lines)-maxLogLines*9/10:]
+		if !m.paused {
+			m.render()
+			m.view.GotoBottom()
+		}
+		return
+	}
+	if m.paused {
+		return
+	}
+	atBottom := m.view.AtBottom()
+	for _, line := range lines {
+		m.add(line)
+	}
+	m.view.SetContent(strings.Join(m.rendered, "\n"))
+	if atBottom {
+		m.view.GotoBottom()
+	}
+}
+
+// render renders all lines again, after the width, the search or the
+// wrapping changed.
+func (m *logsModel) render() {
+	m.shown, m.rendered, m.offsets = m.shown[:0], m.rendered[:0], m.offsets[:0]
+	for _, line := range m.lines {
+		m.add(line)
+	}
+	m.view.SetContent(strings.Join(m.rendered, "\n"))
+}
+
+func (m *logsModel) add(line string) {
+	m.shown = append(m.shown, line)
+	if m.query != nil {
+		line = m.query.ReplaceAllStringFunc(line, func(s string) string {
+			return styles.HighlightStyle.Render(s)
+		})
+	}
+	style := lipgloss.NewStyle().MaxWidth(m.view.Width)
+	if m.wrap {
+		style = lipgloss.NewStyle().Width(m.view.Width)
+	}
+	line = style.Render(line)
+	offset := 0
+	if n := len(m.rendered); n > 0 {
+		offset = m.offsets[n-1] + lipgloss.Height(m.rendered[n-1])
+	}
+	m.rendered = append(m.rendered, line)
+	m.offsets = append(m.offsets, offset)
+}
+
+// jump scrolls to the first matching line after the row from, or the last
+// one before it if direction is negative.
+func (m *logsModel) jump(direction int, from int) {
+	if m.query == nil || len(m.rendered) == 0 {
+		return
+	}
+	for i := range m.rendered {
+		index := i
+		if direction < 0 {
+			index = len(m.rendered) - 1 - i
+		}
+		offset := m.offsets[index]
+		if (direction > 0 && offset <= from) || (direction < 0 && offset >= from) {
+			continue
+		}
+		if m.query.MatchString(m.shown[index]) {
+			m.view.SetYOffset(offset)
+			return
+		}
+	}
+	m.notice = "no more matches for " + m.search.Value()
+}
+
+func (m *logsModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(logBanner))
+	context := utils.ViewContext()
+	title := styles.HeaderStyle.Render(fmt.Sprintf("%s/%s/%s", m.namespace, m.pod, m.options.Container)) + "  " + m.describe()
+	footer := styles.HelpStyle.Render("/ search • n/N next/prev match • space pause • w wrap • f follow • p previous • t timestamps • s since • T tail • q back")
+	if m.search.Focused() {
+		footer = styles.HelpStyle.Render(m.search.View())
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, title, m.view.View(), footer)
+}
+
+// describe summarizes the options and the state of the stream.
+func (m *logsModel) describe() string {
+	var parts []string
+	if m.options.Follow {
+		parts = append(parts, "follow")
+	}
+	if m.options.Previous {
+		parts = append(parts, "previous")
+	}
+	if m.options.Timestamps {
+		parts = append(parts, "timestamps")
+	}
+	if m.options.Since > 0 {
+		parts = append(parts, "since "+m.options.Since.String())
+	}
+	if m.options.TailLines > 0 {
+		parts = append(parts, fmt.Sprintf("tail %d", m.options.TailLines))
+	}
+	if m.wrap {
+		parts = append(parts, "wrap")
+	}
+	if m.paused {
+		parts = append(parts, styles.TerminatingStyle.Render(fmt.Sprintf("paused, %d new lines", m.pending)))
+	}
+	for _, s := range []string{m.status, m.notice} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " • ")
+}
+
+// nextStep returns the step after current, wrapping around at the end.
+func nextStep[T comparable](steps []T, current T) T {
+	for i, step := range steps {
+		if step == current {
+			return steps[(i+1)%len(steps)]
+		}
+	}
+	return steps[0]
+}
+
+// logContainer picks the container whose logs are shown for a pod: the
+// default container, or the first regular one.
+func logContainer(containers []k8s.PodContainer) string {
+	if name, ok := k8s.DefaultContainer(containers); ok {
+		return name
+	}
+	for _, c := range containers {
+		if c.Kind == k8s.ContainerRegular {
+			return c.Name
+		}
+	}
+	return ""
+}
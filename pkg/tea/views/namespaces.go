@@ -1,6 +1,8 @@
 package views
 
 import (
+	"path"
+
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -8,7 +10,7 @@ import (
 	"github.com/samox73/ksh/pkg/tea/components"
 	"github.com/samox73/ksh/pkg/tea/styles"
 	"github.com/samox73/ksh/pkg/tea/utils"
-	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/api/core/v1"
 )
 
 const namespaceBanner = `
@@ -19,10 +21,15 @@ const namespaceBanner = `
 ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗███████║██║     ██║  ██║╚██████╗███████╗
 ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝╚══════╝╚═╝     ╚═╝  ╚═╝ ╚═════╝╚══════╝`
 
+// HiddenNamespaces are left out of the namespace list. They are patterns as
+// in path.Match, e.g. kube-*.
+var HiddenNamespaces []string
+
 type namespacesModel struct {
-	items     list.Model
-	clientset kubernetes.Clientset
-	banner    string
+	items  list.Model
+	client k8s.Client
+	banner string
+	parent tea.Model
 }
 
 func (m namespacesModel) Init() tea.Cmd {
@@ -37,13 +44,33 @@ func (m *namespacesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.items.SetHeight(msg.Height - lipgloss.Height(m.banner) - len(i.Labels) - 2)
 		return m, nil
 	case tea.KeyMsg:
-		switch keypress := msg.String(); keypress {
+		switch keypress := keyOf(msg); keypress {
 		case "ctrl+c":
 			return m, tea.Quit
+		case "q":
+			if m.parent != nil {
+				return switchTo(m.parent)
+			}
 		case "enter":
 			i, ok := m.items.SelectedItem().(components.Item)
 			if ok {
-				return buildPodModel(i.Name, m), tea.ClearScreen
+				return switchTo(buildWorkloadModel(m.client, i.Name, m))
+			}
+		case "P":
+			if m.items.FilterState() != list.Filtering {
+				return switchTo(newForwardsModel(m))
+			}
+		case "A":
+			if m.items.FilterState() != list.Filtering {
+				return switchTo(newSearchModel(m.client, k8s.PodSelector{}, m))
+			}
+		case "N":
+			if m.items.FilterState() != list.Filtering {
+				return switchTo(buildNodeModel(m.client, m))
+			}
+		case "R":
+			if m.items.FilterState() != list.Filtering {
+				return switchTo(buildLaunchModel(m.client))
 			}
 		}
 	}
@@ -60,8 +87,28 @@ func (m *namespacesModel) View() string {
 	return lipgloss.JoinVertical(lipgloss.Left, banner, context, items)
 }
 
-func BuildNamespaceModel() *namespacesModel {
-	clientset := *k8s.GetKubernetesClientset()
-	namespaces := k8s.GetNamespaces(clientset).Items
-	return &namespacesModel{items: utils.BuildNamespaceList(namespaces), clientset: clientset, banner: styles.GetBanner(namespaceBanner)}
+func BuildNamespaceModel(client k8s.Client, parent tea.Model) tea.Model {
+	retry := func() (tea.Model, tea.Cmd) { return switchTo(BuildNamespaceModel(client, parent)) }
+	namespaces, err := client.Namespaces()
+	if err != nil {
+		return newErrorModel(err, retry, parent)
+	}
+	return &namespacesModel{items: utils.BuildNamespaceList(visibleNamespaces(namespaces)), client: client, banner: styles.GetBanner(namespaceBanner), parent: parent}
+}
+
+func visibleNamespaces(namespaces []corev1.Namespace) []corev1.Namespace {
+	visible := namespaces[:0:0]
+	for _, ns := range namespaces {
+		hidden := false
+		for _, pattern := range HiddenNamespaces {
+			if ok, _ := path.Match(pattern, ns.Name); ok {
+				hidden = true
+				break
+			}
+		}
+		if !hidden {
+			visible = append(visible, ns)
+		}
+	}
+	return visible
 }
@@ -0,0 +1,102 @@
+package views
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/components"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const nodeBanner = `
+███╗   ██╗ ██████╗ ██████╗ ███████╗███████╗
+████╗  ██║██╔═══██╗██╔══██╗██╔════╝██╔════╝
+██╔██╗ ██║██║   ██║██║  ██║█████╗  ███████╗
+██║╚██╗██║██║   ██║██║  ██║██╔══╝  ╚════██║
+██║ ╚████║╚██████╔╝██████╔╝███████╗███████║
+╚═╝  ╚═══╝ ╚═════╝ ╚═════╝ ╚══════╝╚══════╝`
+
+// nodesModel lists the nodes of the cluster and opens shells on them.
+type nodesModel struct {
+	items  list.Model
+	client k8s.Client
+	status string
+	parent tea.Model
+}
+
+func (m nodesModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *nodesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	i, _ := m.items.SelectedItem().(components.Item)
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.items.SetWidth(msg.Width)
+		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(nodeBanner)-len(i.Labels)-3, len(m.items.Items())+7))
+		return m, nil
+	case sessionEndedMsg:
+		m.status = sessionStatus(msg)
+		m.reload()
+		return m, nil
+	case tea.KeyMsg:
+		switch keypress := keyOf(msg); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "q":
+			return switchTo(m.parent)
+		case "enter":
+			if i, ok := m.items.SelectedItem().(components.Item); ok {
+				return m, startNodeShell(m.client, i.Name)
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.items, cmd = m.items.Update(msg)
+	return m, cmd
+}
+
+// reload refreshes the node list after a shell, keeping the selection.
+func (m *nodesModel) reload() {
+	nodes, err := m.client.Nodes()
+	if err != nil {
+		return
+	}
+	selected, _ := m.items.SelectedItem().(components.Item)
+	width, height := m.items.Width(), m.items.Height()
+	m.items = buildNodeList(nodes)
+	m.items.SetSize(width, height)
+	utils.SelectItem(&m.items, selected.Name)
+}
+
+func (m *nodesModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(nodeBanner))
+	context := utils.ViewContext()
+	labels := ""
+	if i, ok := m.items.SelectedItem().(components.Item); ok {
+		labels = utils.ViewLabels(i.Labels)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, m.status, labels, m.items.View())
+}
+
+func buildNodeList(nodes []corev1.Node) list.Model {
+	l := utils.BuildNodeList(nodes)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "node shell"))}
+	}
+	return l
+}
+
+func buildNodeModel(client k8s.Client, parent tea.Model) tea.Model {
+	retry := func() (tea.Model, tea.Cmd) { return switchTo(buildNodeModel(client, parent)) }
+	nodes, err := client.Nodes()
+	if err != nil {
+		return newErrorModel(err, retry, parent)
+	}
+	return &nodesModel{items: buildNodeList(nodes), client: client, parent: parent}
+}
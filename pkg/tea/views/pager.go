@@ -0,0 +1,83 @@
+package views
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+	"golang.org/x/term"
+)
+
+// pagerLimit is how much of a file the pager reads.
+const pagerLimit = 1 << 20
+
+// pagerModel shows a text file of a container.
+type pagerModel struct {
+	view   viewport.Model
+	title  string
+	parent tea.Model
+}
+
+func newPagerModel(title string, content string, parent tea.Model) *pagerModel {
+	width, height := terminalSize()
+	view := viewport.New(width, pagerHeight(height))
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	view.SetContent(strings.ReplaceAll(content, "\t", "    "))
+	return &pagerModel{view: view, title: title, parent: parent}
+}
+
+// terminalSize returns the size of the terminal, for views that need it
+// before the first tea.WindowSizeMsg arrives.
+func terminalSize() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 80, 24
+	}
+	return width, height
+}
+
+func pagerHeight(height int) int {
+	return max(height-lipgloss.Height(filesBanner)-6, 3)
+}
+
+func (m *pagerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *pagerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.view.Width = msg.Width
+		m.view.Height = pagerHeight(msg.Height)
+		return m, nil
+	case tea.KeyMsg:
+		switch keyOf(msg) {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "q", "esc":
+			return switchTo(m.parent)
+		case "g", "home":
+			m.view.GotoTop()
+			return m, nil
+		case "G", "end":
+			m.view.GotoBottom()
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.view, cmd = m.view.Update(msg)
+	return m, cmd
+}
+
+func (m *pagerModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(filesBanner))
+	context := utils.ViewContext()
+	title := styles.HeaderStyle.Render(m.title) + fmt.Sprintf("  %3.f%%", m.view.ScrollPercent()*100)
+	help := styles.HelpStyle.Render("↑/k up • ↓/j down • b/pgup • f/pgdown • g/G top/bottom • q back")
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, title, m.view.View(), help)
+}
@@ -1,17 +1,19 @@
 package views
 
 import (
-	"fmt"
-	"sort"
+	"errors"
+	"slices"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/samox73/ksh/pkg/k8s"
 	"github.com/samox73/ksh/pkg/tea/components"
 	"github.com/samox73/ksh/pkg/tea/styles"
 	"github.com/samox73/ksh/pkg/tea/utils"
-	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/api/core/v1"
 )
 
 const podBanner = `
@@ -22,20 +24,64 @@ const podBanner = `
 ██║     ╚██████╔╝██████╔╝
 ╚═╝      ╚═════╝ ╚═════╝`
 
+// markerDuration is how long pods that appeared or disappeared stay marked
+// in the list.
+const markerDuration = 5 * time.Second
+
+type podEventsMsg struct {
+	watcher *k8s.PodWatcher
+	events  []k8s.PodEvent
+}
+
+type podRefreshMsg struct{}
+
 type PodsModel struct {
 	items     list.Model
 	namespace string
-	pod       string
-	clientset kubernetes.Clientset
-	parent    tea.Model
+	// selector picks the pods of a workload, filter is the selector the
+	// user typed or built from labels.
+	selector k8s.PodSelector
+	filter   k8s.PodSelector
+	input    textinput.Model
+	// labelCursor is the selected label of the current pod while the
+	// labels are being picked, -1 otherwise.
+	labelCursor int
+	// checked are the pods picked for a command in several pods.
+	checked map[string]bool
+	pod     string
+	client  k8s.Client
+	parent  tea.Model
+	status  string
+	watcher *k8s.PodWatcher
+	added   map[string]time.Time
+	deleted map[string]deletedPod
+}
+
+type deletedPod struct {
+	pod corev1.Pod
+	at  time.Time
 }
 
-func (m PodsModel) GetPod() string                      { return m.pod }
-func (m PodsModel) GetNamespace() string                { return m.namespace }
-func (m PodsModel) GetClientset() *kubernetes.Clientset { return &m.clientset }
+func (m PodsModel) GetPod() string        { return m.pod }
+func (m PodsModel) GetNamespace() string  { return m.namespace }
+func (m PodsModel) GetClient() k8s.Client { return m.client }
 
 func (m PodsModel) Init() tea.Cmd {
-	return nil
+	if m.watcher == nil {
+		return nil
+	}
+	refresh := func() tea.Msg { return podRefreshMsg{} }
+	return tea.Batch(refresh, waitForPodEvents(m.watcher))
+}
+
+func waitForPodEvents(w *k8s.PodWatcher) tea.Cmd {
+	return func() tea.Msg {
+		events, ok := w.Next()
+		if !ok {
+			return nil
+		}
+		return podEventsMsg{watcher: w, events: events}
+	}
 }
 
 func (m PodsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -43,29 +89,115 @@ func (m PodsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.items.SetWidth(msg.Width)
-		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(podBanner)-len(i.Labels), len(m.items.Items())))
+		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(podBanner)-len(i.Labels)-2, len(m.items.Items())+7))
+		return m, nil
+	case podEventsMsg:
+		if msg.watcher != m.watcher {
+			return m, nil
+		}
+		now := time.Now()
+		for _, e := range msg.events {
+			switch e.Type {
+			case k8s.PodAdded:
+				m.added[e.Pod.Name] = now
+				delete(m.deleted, e.Pod.Name)
+			case k8s.PodDeleted:
+				m.deleted[e.Pod.Name] = deletedPod{pod: e.Pod, at: now}
+				delete(m.added, e.Pod.Name)
+			}
+		}
+		expire := tea.Tick(markerDuration, func(time.Time) tea.Msg { return podRefreshMsg{} })
+		return m, tea.Batch(m.refresh(), waitForPodEvents(m.watcher), expire)
+	case podRefreshMsg:
+		return m, m.refresh()
+	case debugStartedMsg:
+		m.status = ""
+		if errors.Is(msg.err, k8s.ErrNotConfirmed) {
+			m.status = debugCancelledStatus(msg.pod)
+			return m, nil
+		}
+		if msg.err != nil {
+			retry := func() (tea.Model, tea.Cmd) {
+				m.status = debugStatus(msg.pod)
+				return m, startDebugContainer(m.client, m.namespace, msg.pod, "")
+			}
+			return switchTo(newErrorModel(msg.err, retry, m))
+		}
+		m.pod = msg.pod
+		return m, startAttach(m.client, m.namespace, msg.pod, msg.container)
+	case sessionEndedMsg:
+		m.status = sessionStatus(msg)
+		return m, nil
+	case broadcastEndedMsg:
+		m.status = broadcastStatus(msg)
 		return m, nil
 	case tea.KeyMsg:
-		switch keypress := msg.String(); keypress {
+		if m.input.Focused() {
+			return m.updateInput(msg)
+		}
+		if m.labelCursor >= 0 {
+			return m.updateLabels(msg)
+		}
+		switch keypress := keyOf(msg); keypress {
 		case "q":
-			return m.parent, nil
+			if m.watcher != nil {
+				m.watcher.Stop()
+			}
+			return switchTo(m.parent)
 		case "enter":
 			i, ok := m.items.SelectedItem().(components.Item)
 			m.pod = i.Name
 			if ok {
-				clientset := *k8s.GetKubernetesClientset()
-				containers := k8s.GetContainers(clientset, m.namespace, m.pod)
-				if len(containers) == 1 {
-					return ContainersModel{
-						items:     utils.BuildContainerList(containers),
-						clientset: clientset,
-						namespace: m.namespace,
-						pod:       m.pod,
-						container: containers[0].Name,
-						parent:    m,
-					}, tea.Quit
+				return m.openPod()
+			}
+		case "D":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				m.status = debugStatus(i.Name)
+				return m, startDebugContainer(m.client, m.namespace, i.Name, "")
+			}
+		case "F":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				m.pod = i.Name
+				return switchTo(newPortsModel(m.client, m.namespace, i.Name, m))
+			}
+		case "P":
+			if m.items.FilterState() != list.Filtering {
+				return switchTo(newForwardsModel(m))
+			}
+		case "L":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				m.pod = i.Name
+				return m.openLogs()
+			}
+		case "S":
+			if m.items.FilterState() != list.Filtering {
+				m.input.Prompt = "selector: "
+				m.input.SetValue(m.filter.String())
+				m.input.CursorEnd()
+				return m, m.input.Focus()
+			}
+		case " ":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && m.items.FilterState() != list.Filtering {
+				if m.checked[i.Name] {
+					delete(m.checked, i.Name)
+				} else {
+					m.checked[i.Name] = true
 				}
-				return buildContainerModel(m.namespace, m.pod, m), tea.ClearScreen
+				return m, m.refresh()
+			}
+		case "X":
+			if m.items.FilterState() != list.Filtering {
+				return m.openBroadcast()
+			}
+		case "tab":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok && len(i.Labels) > 0 && m.items.FilterState() != list.Filtering {
+				m.labelCursor = 0
+				return m, nil
 			}
 		}
 	}
@@ -75,47 +207,200 @@ func (m PodsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *PodsModel) viewLabels() string {
-	i, ok := m.items.SelectedItem().(components.Item)
-	if !ok {
-		return ""
+func (m PodsModel) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		filter, err := k8s.ParseSelector(m.input.Value())
+		if err != nil {
+			m.status = styles.StatusStyle.Render(err.Error())
+			return m, nil
+		}
+		m.input.Blur()
+		return m.setFilter(filter)
 	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
 
-	l := ""
-	keys := make([]string, 0, len(i.Labels))
-	longestKeyLength := 0
-	for k := range i.Labels {
-		keys = append(keys, k)
-		if len(k) > longestKeyLength {
-			longestKeyLength = len(k)
+// updateLabels moves through the labels of the selected pod and adds the
+// picked one to the filter.
+func (m PodsModel) updateLabels(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	i, _ := m.items.SelectedItem().(components.Item)
+	keys := utils.LabelKeys(i.Labels)
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		m.labelCursor = max(m.labelCursor-1, 0)
+	case "down", "j":
+		m.labelCursor = min(m.labelCursor+1, len(keys)-1)
+	case "enter":
+		if m.labelCursor < len(keys) {
+			key := keys[m.labelCursor]
+			m.labelCursor = -1
+			return m.setFilter(m.filter.And(k8s.PodSelector{Labels: key + "=" + i.Labels[key]}))
+		}
+	case "tab", "esc", "q":
+		m.labelCursor = -1
+	}
+	return m, nil
+}
+
+// setFilter restarts the watch with the new filter. The current pods stay
+// if the API server rejects it.
+func (m PodsModel) setFilter(filter k8s.PodSelector) (tea.Model, tea.Cmd) {
+	watcher, err := m.client.WatchPods(m.namespace, m.selector.And(filter))
+	if err != nil {
+		m.status = styles.StatusStyle.Render(err.Error())
+		return m, nil
+	}
+	if m.watcher != nil {
+		m.watcher.Stop()
+	}
+	m.watcher, m.filter, m.status = watcher, filter, ""
+	m.added, m.deleted = map[string]time.Time{}, map[string]deletedPod{}
+	cmd := m.refresh()
+	return m, tea.Batch(tea.ClearScreen, cmd, waitForPodEvents(watcher))
+}
+
+// refresh rebuilds the list from the watcher's store, keeping the selection
+// on the same pod and expiring markers that are older than markerDuration.
+func (m *PodsModel) refresh() tea.Cmd {
+	if m.watcher == nil {
+		return nil
+	}
+	now := time.Now()
+	markers := map[string]components.Marker{}
+	for name, at := range m.added {
+		if now.Sub(at) >= markerDuration {
+			delete(m.added, name)
+			continue
 		}
+		markers[name] = components.MarkerAdded
 	}
-	sort.Strings(keys)
-	for j, k := range keys {
-		l += fmt.Sprintf("%*s: %s", longestKeyLength, k, i.Labels[k])
-		if j != len(i.Labels)-1 {
-			l += "\n"
+	pods := m.watcher.Pods()
+	for name, d := range m.deleted {
+		if now.Sub(d.at) >= markerDuration {
+			delete(m.deleted, name)
+			continue
 		}
+		markers[name] = components.MarkerDeleted
+		pods = append(pods, d.pod)
 	}
-	return lipgloss.NewStyle().Margin(0, 0, 0, 2).Border(lipgloss.NormalBorder(), true).Render(l)
+
+	selected, _ := m.items.SelectedItem().(components.Item)
+	for name := range m.checked {
+		if !slices.ContainsFunc(pods, func(p corev1.Pod) bool { return p.Name == name }) {
+			delete(m.checked, name)
+		}
+	}
+	cmd := utils.SetPodItems(&m.items, pods, markers, m.checked)
+	utils.SelectItem(&m.items, selected.Name)
+	return cmd
+}
+
+func (m PodsModel) openPod() (tea.Model, tea.Cmd) {
+	pod, err := m.client.Pod(m.namespace, m.pod)
+	if err != nil {
+		return switchTo(newErrorModel(err, m.openPod, m))
+	}
+	containers := k8s.PodContainers(pod)
+	if name, ok := k8s.DefaultContainer(containers); ok && k8s.CheckExecutable(pod, name) == nil {
+		return m, startSession(m.client, m.namespace, m.pod, name, nil, false)
+	}
+	return switchTo(buildContainerModel(m.client, m.namespace, m.pod, m))
+}
+
+// openBroadcast prompts for a command to run in the checked pods or, if
+// none are checked, in all listed pods.
+func (m PodsModel) openBroadcast() (tea.Model, tea.Cmd) {
+	names := map[string]bool{}
+	for name := range m.checked {
+		names[name] = true
+	}
+	if len(names) == 0 {
+		for _, item := range m.items.VisibleItems() {
+			if i, ok := item.(components.Item); ok && i.Marker != components.MarkerDeleted {
+				names[i.Name] = true
+			}
+		}
+	}
+	var pods []corev1.Pod
+	for _, pod := range m.watcher.Pods() {
+		if names[pod.Name] {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		return m, nil
+	}
+	return switchTo(newBroadcastModel(k8s.BroadcastTargets(pods, ""), m))
+}
+
+func (m PodsModel) openLogs() (tea.Model, tea.Cmd) {
+	containers, err := m.client.Containers(m.namespace, m.pod)
+	if err != nil {
+		return switchTo(newErrorModel(err, m.openLogs, m))
+	}
+	return switchTo(newLogsModel(m.client, m.namespace, m.pod, logContainer(containers), m))
+}
+
+func (m *PodsModel) viewLabels() string {
+	i, ok := m.items.SelectedItem().(components.Item)
+	if !ok {
+		return ""
+	}
+	return utils.ViewSelectableLabels(i.Labels, m.labelCursor)
 }
 
 func (m PodsModel) View() string {
 	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(podBanner))
 	context := utils.ViewContext()
+	if m.selector.Owner != nil {
+		context = lipgloss.JoinVertical(lipgloss.Left, context, styles.HeaderStyle.Render("pods of "+utils.WorkloadName(*m.selector.Owner)))
+	}
+	if filter := m.filter.String(); filter != "" {
+		context = lipgloss.JoinVertical(lipgloss.Left, context, styles.HeaderStyle.Render("selector: "+filter))
+	}
 	labels := m.viewLabels()
 	items := m.items.View()
-	return lipgloss.JoinVertical(lipgloss.Left, banner, context, labels, items)
+	switch {
+	case m.input.Focused():
+		items = lipgloss.JoinVertical(lipgloss.Left, styles.PromptStyle.Render(m.input.View()),
+			styles.HelpStyle.Render("e.g. app=api,tier!=cache,status.phase=Running • enter apply • esc cancel"))
+	case m.labelCursor >= 0:
+		items = lipgloss.JoinVertical(lipgloss.Left, styles.HelpStyle.Render("↑/↓ pick label • enter add to selector • tab done"), items)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, m.status, labels, items)
 }
 
-func buildPodModel(namespace string, parent tea.Model) *PodsModel {
-	clientset := *k8s.GetKubernetesClientset()
-	pods := k8s.GetPods(clientset, namespace).Items
+func buildPodModel(client k8s.Client, namespace string, selector k8s.PodSelector, filter k8s.PodSelector, parent tea.Model) tea.Model {
+	retry := func() (tea.Model, tea.Cmd) {
+		return switchTo(buildPodModel(client, namespace, selector, filter, parent))
+	}
+	watcher, err := client.WatchPods(namespace, selector.And(filter))
+	if err != nil {
+		return newErrorModel(err, retry, parent)
+	}
 	m := &PodsModel{
-		items:     utils.BuildPodList(pods),
-		clientset: clientset,
-		namespace: namespace,
-		parent:    parent,
+		items:       utils.BuildPodList(watcher.Pods()),
+		client:      client,
+		namespace:   namespace,
+		selector:    selector,
+		filter:      filter,
+		input:       textinput.New(),
+		labelCursor: -1,
+		checked:     map[string]bool{},
+		parent:      parent,
+		watcher:     watcher,
+		added:       map[string]time.Time{},
+		deleted:     map[string]deletedPod{},
 	}
 	return m
 }
@@ -0,0 +1,160 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/components"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podPageSize is how many pods are requested at once when listing the pods
+// of all namespaces, so that large clusters are listed in parts instead of
+// timing out.
+const podPageSize = 500
+
+type podPageMsg struct {
+	model   *searchModel
+	request int
+	pods    *corev1.PodList
+	err     error
+}
+
+// searchModel lists the pods of all namespaces. Pages are loaded one after
+// the other in the background, so the pods can be filtered while the rest
+// is still loading.
+type searchModel struct {
+	items    list.Model
+	pods     []corev1.Pod
+	client   k8s.Client
+	selector k8s.PodSelector
+	// next is the continue token of the next page, done is set once the
+	// last page has been loaded. request tells the answer to the latest
+	// request apart from ones that were sent before the view was left.
+	next    string
+	done    bool
+	request int
+	status  string
+	width   int
+	height  int
+	parent  tea.Model
+}
+
+func newSearchModel(client k8s.Client, selector k8s.PodSelector, parent tea.Model) *searchModel {
+	items := utils.BuildGlobalPodList(nil)
+	items.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "containers")),
+			key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "reload")),
+		}
+	}
+	return &searchModel{items: items, client: client, selector: selector, parent: parent}
+}
+
+// Init loads the next page, also when returning from the container view
+// while the pods were still loading.
+func (m *searchModel) Init() tea.Cmd {
+	if m.done {
+		return nil
+	}
+	m.request++
+	return m.loadPage(m.request, m.next)
+}
+
+func (m *searchModel) loadPage(request int, continueToken string) tea.Cmd {
+	client, selector := m.client, m.selector
+	return func() tea.Msg {
+		pods, err := client.PodPage("", selector, podPageSize, continueToken)
+		return podPageMsg{model: m, request: request, pods: pods, err: err}
+	}
+}
+
+func (m *searchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+		return m, nil
+	case podPageMsg:
+		if msg.model != m || msg.request != m.request {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.done = true
+			m.status = styles.StatusStyle.Render(fmt.Sprintf("loaded %d pods, then %v; press r to reload", len(m.pods), msg.err))
+			return m, nil
+		}
+		m.pods = append(m.pods, msg.pods.Items...)
+		m.next = msg.pods.Continue
+		m.done = m.next == ""
+		m.status = m.progress(msg.pods.RemainingItemCount)
+		cmd := utils.SetGlobalPodItems(&m.items, m.pods)
+		m.resize()
+		if m.done {
+			return m, cmd
+		}
+		return m, tea.Batch(cmd, m.loadPage(m.request, m.next))
+	case tea.KeyMsg:
+		if m.items.FilterState() == list.Filtering {
+			break
+		}
+		switch keypress := keyOf(msg); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "q":
+			m.request++
+			return switchTo(m.parent)
+		case "r":
+			m.pods, m.next, m.done = nil, "", false
+			m.status = ""
+			return m, m.Init()
+		case "enter":
+			if i, ok := m.items.SelectedItem().(components.Item); ok {
+				return switchTo(buildContainerModel(m.client, i.Columns[0], i.Name, m))
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.items, cmd = m.items.Update(msg)
+	return m, cmd
+}
+
+func (m *searchModel) progress(remaining *int64) string {
+	if !m.done {
+		more := ""
+		if remaining != nil {
+			more = fmt.Sprintf(" of about %d", int64(len(m.pods))+*remaining)
+		}
+		return styles.StatusStyle.Render(fmt.Sprintf("loaded %d pods%s, loading more...", len(m.pods), more))
+	}
+	namespaces := map[string]bool{}
+	for _, pod := range m.pods {
+		namespaces[pod.Namespace] = true
+	}
+	return styles.StatusStyle.Render(fmt.Sprintf("%d pods in %d namespaces", len(m.pods), len(namespaces)))
+}
+
+func (m *searchModel) resize() {
+	if m.width == 0 {
+		return
+	}
+	m.items.SetWidth(m.width)
+	m.items.SetHeight(utils.MinInt(m.height-lipgloss.Height(podBanner)-5, len(m.items.Items())+7))
+}
+
+func (m *searchModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(podBanner))
+	context := utils.ViewContext()
+	title := "pods in all namespaces"
+	if selector := m.selector.String(); selector != "" {
+		title += ", selector: " + selector
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, styles.HeaderStyle.Render(title), m.status, m.items.View())
+}
@@ -0,0 +1,147 @@
+package views
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/styles"
+)
+
+// defaultCommand is run instead of a shell when a container is picked, if
+// set. It comes from the command line.
+var (
+	defaultCommand []string
+	defaultTTY     bool
+)
+
+// SetDefaultCommand makes the TUI run command instead of a shell in the
+// containers that are picked.
+func SetDefaultCommand(command []string, tty bool) {
+	defaultCommand, defaultTTY = command, tty
+}
+
+// execProcess hands the terminal to a command until it is done. Tests
+// replace it to run the command right away.
+var execProcess = tea.Exec
+
+type sessionEndedMsg struct {
+	target string
+	err    error
+}
+
+// sessionCommand runs a session while Bubble Tea has released the terminal.
+// If node is set, it opens a shell on that node instead, and if attach is
+// set, it attaches to the shell of the session's debug container.
+type sessionCommand struct {
+	client  k8s.Client
+	session k8s.Session
+	node    string
+	attach  bool
+	stdin   io.Reader
+	stdout  io.Writer
+}
+
+func (c *sessionCommand) SetStdin(r io.Reader)  { c.stdin = r }
+func (c *sessionCommand) SetStdout(w io.Writer) { c.stdout, c.session.Stdout = w, w }
+func (c *sessionCommand) SetStderr(w io.Writer) { c.session.Stderr = w }
+
+// Run runs the session. For commands without a TTY, and for sessions that
+// failed without an exit code, it waits for enter so the output can be read
+// before the TUI takes over the screen again. Interactive sessions pass on
+// the exit code of their last command, which is no failure of the session.
+// Debug containers were confirmed when they were started, so attaching to
+// them does not ask again.
+func (c *sessionCommand) Run() error {
+	if !c.attach {
+		if err := k8s.Confirm(c.stdin, c.stdout, "open a session"); err != nil {
+			return err
+		}
+	}
+	var err error
+	switch {
+	case c.node != "":
+		fmt.Fprintf(c.stdout, "Opening shell on node %s\n", c.node)
+		err = c.client.NodeShell(c.node)
+	case c.attach:
+		fmt.Fprintf(c.stdout, "Attaching to %s/%s/%s\n", c.session.Namespace, c.session.Pod, c.session.Container)
+		err = c.client.Attach(c.session.Namespace, c.session.Pod, c.session.Container)
+	case len(c.session.Command) == 0:
+		fmt.Fprintf(c.stdout, "Opening shell to %s/%s/%s\n", c.session.Namespace, c.session.Pod, c.session.Container)
+		fallthrough
+	default:
+		err = c.session.Run(c.client)
+	}
+	failed := err != nil && k8s.ExitCode(err) <= 0
+	if failed || (len(c.session.Command) > 0 && !c.session.TTY) {
+		if failed {
+			fmt.Fprintf(c.stdout, "\nError: %v\n", err)
+		}
+		fmt.Fprintf(c.stdout, "\n[exit code %d] press enter to return to ksh", max(0, k8s.ExitCode(err)))
+		if c.stdin != nil {
+			_, _ = bufio.NewReader(c.stdin).ReadString('\n')
+		}
+	}
+	return err
+}
+
+// startSession suspends the TUI, runs a shell or command in the container and
+// resumes the TUI with a sessionEndedMsg once it is done.
+func startSession(client k8s.Client, namespace string, pod string, container string, command []string, tty bool) tea.Cmd {
+	if len(command) == 0 {
+		command, tty = defaultCommand, defaultTTY
+	}
+	c := &sessionCommand{
+		client: client,
+		session: k8s.Session{
+			Namespace: namespace,
+			Pod:       pod,
+			Container: container,
+			Command:   command,
+			TTY:       tty,
+		},
+	}
+	target := namespace + "/" + pod + "/" + container
+	return execProcess(c, func(err error) tea.Msg {
+		return sessionEndedMsg{target: target, err: err}
+	})
+}
+
+// startAttach suspends the TUI, attaches to the shell of a debug container
+// and resumes the TUI once the shell exits.
+func startAttach(client k8s.Client, namespace string, pod string, container string) tea.Cmd {
+	c := &sessionCommand{
+		client:  client,
+		session: k8s.Session{Namespace: namespace, Pod: pod, Container: container},
+		attach:  true,
+	}
+	target := namespace + "/" + pod + "/" + container
+	return execProcess(c, func(err error) tea.Msg {
+		return sessionEndedMsg{target: target, err: err}
+	})
+}
+
+// startNodeShell suspends the TUI, opens a shell on the node through a
+// privileged pod and resumes the TUI once it is done.
+func startNodeShell(client k8s.Client, node string) tea.Cmd {
+	c := &sessionCommand{client: client, node: node}
+	return execProcess(c, func(err error) tea.Msg {
+		return sessionEndedMsg{target: "node " + node, err: err}
+	})
+}
+
+func sessionStatus(msg sessionEndedMsg) string {
+	if msg.err == nil {
+		return styles.StatusStyle.Render("session in " + msg.target + " ended")
+	}
+	if errors.Is(msg.err, k8s.ErrNotConfirmed) {
+		return styles.StatusStyle.Render("session in " + msg.target + " cancelled")
+	}
+	if code := k8s.ExitCode(msg.err); code > 0 {
+		return styles.StatusStyle.Render(fmt.Sprintf("session in %s ended with exit code %d", msg.target, code))
+	}
+	return styles.StatusStyle.Render("session in " + msg.target + " failed: " + msg.err.Error())
+}
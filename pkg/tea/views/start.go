@@ -0,0 +1,42 @@
+package views
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+// BuildPodModelFor opens the TUI at the pod list of the given namespace,
+// narrowed by filter, with the workload and namespace lists as its parents.
+func BuildPodModelFor(client k8s.Client, namespace string, filter k8s.PodSelector) tea.Model {
+	var parent tea.Model = BuildNamespaceModel(client, BuildContextModel())
+	if m, ok := parent.(*namespacesModel); ok {
+		utils.SelectItem(&m.items, namespace)
+	}
+	if m, ok := buildWorkloadModel(client, namespace, parent).(*workloadsModel); ok {
+		parent = m
+	}
+	return buildPodModel(client, namespace, k8s.PodSelector{}, filter, parent)
+}
+
+// BuildSearchModel opens the TUI at the pods of all namespaces that match
+// the selector, with the namespace list as its parent.
+func BuildSearchModel(client k8s.Client, selector k8s.PodSelector) tea.Model {
+	return newSearchModel(client, selector, BuildNamespaceModel(client, BuildContextModel()))
+}
+
+// BuildContainerModelFor opens the TUI at the container list of the given
+// pod, with the pod and namespace lists as its parents.
+func BuildContainerModelFor(client k8s.Client, namespace string, pod string) tea.Model {
+	parent := BuildPodModelFor(client, namespace, k8s.PodSelector{})
+	if m, ok := parent.(*PodsModel); ok {
+		utils.SelectItem(&m.items, pod)
+	}
+	return buildContainerModel(client, namespace, pod, parent)
+}
+
+// switchTo makes m the active model and starts its commands, since Bubble
+// Tea only calls Init on the initial model.
+func switchTo(m tea.Model) (tea.Model, tea.Cmd) {
+	return m, tea.Batch(tea.ClearScreen, m.Init())
+}
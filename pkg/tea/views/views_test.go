@@ -0,0 +1,489 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/k8s/fake"
+	"github.com/samox73/ksh/pkg/tea/components"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func namespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+	}
+}
+
+// runningPod returns a running pod whose containers have all started.
+func runningPod(namespace string, name string, containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	for _, c := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: c, Image: c})
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name:  c,
+			Image: c,
+			Ready: true,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		})
+	}
+	return pod
+}
+
+// newTestClient serves a small cluster and keeps the files ksh writes, and
+// the kubeconfig it reads, in a temporary directory. Sessions run right away
+// instead of taking over the terminal.
+func newTestClient(t *testing.T) *fake.Client {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("KUBECONFIG", filepath.Join(dir, "kubeconfig"))
+	t.Setenv("XDG_CACHE_HOME", dir)
+	auditLog, launchFile := k8s.AuditLog, k8s.LaunchFile
+	k8s.AuditLog, k8s.LaunchFile = "", filepath.Join(dir, "state.json")
+	t.Cleanup(func() { k8s.AuditLog, k8s.LaunchFile = auditLog, launchFile })
+
+	execProcess = func(c tea.ExecCommand, fn tea.ExecCallback) tea.Cmd {
+		return func() tea.Msg {
+			c.SetStdin(strings.NewReader("\n"))
+			c.SetStdout(io.Discard)
+			c.SetStderr(io.Discard)
+			return fn(c.Run())
+		}
+	}
+	t.Cleanup(func() { execProcess = tea.Exec })
+
+	return fake.NewClient(
+		namespace("default"),
+		namespace("kube-system"),
+		namespace("payments"),
+		runningPod("payments", "api-7f9c", "app", "sidecar"),
+		runningPod("payments", "worker-x2b1", "worker"),
+	)
+}
+
+func keyMsg(k string) tea.KeyMsg {
+	switch k {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	default:
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+	}
+}
+
+// press sends the keys to the model in turn and returns the model and the
+// command of the last one.
+func press(m tea.Model, keys ...string) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	for _, k := range keys {
+		m, cmd = m.Update(keyMsg(k))
+	}
+	return m, cmd
+}
+
+func selected(m tea.Model) string {
+	var item any
+	switch m := m.(type) {
+	case *namespacesModel:
+		item = m.items.SelectedItem()
+	case *workloadsModel:
+		item = m.items.SelectedItem()
+	case *PodsModel:
+		item = m.items.SelectedItem()
+	case PodsModel:
+		item = m.items.SelectedItem()
+	case *ContainersModel:
+		item = m.items.SelectedItem()
+	case ContainersModel:
+		item = m.items.SelectedItem()
+	}
+	i, _ := item.(components.Item)
+	return i.Name
+}
+
+// moveTo presses down until the item is selected.
+func moveTo(t *testing.T, m tea.Model, name string) tea.Model {
+	t.Helper()
+	for n := 0; selected(m) != name; n++ {
+		if n > 20 {
+			t.Fatalf("item %q not found, stuck at %q", name, selected(m))
+		}
+		m, _ = press(m, "down")
+	}
+	return m
+}
+
+// runSession runs the session started by cmd and hands its end back to m.
+func runSession(t *testing.T, m tea.Model, cmd tea.Cmd) tea.Model {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("no session was started")
+	}
+	msg, ok := cmd().(sessionEndedMsg)
+	if !ok {
+		t.Fatalf("expected the session to end, got %T", msg)
+	}
+	m, _ = m.Update(msg)
+	return m
+}
+
+func TestNamespaceEnterOpensWorkloads(t *testing.T) {
+	client := newTestClient(t)
+	m := moveTo(t, BuildNamespaceModel(client, nil), "payments")
+	next, _ := press(m, "enter")
+	workloads, ok := next.(*workloadsModel)
+	if !ok {
+		t.Fatalf("expected the workload list, got %T", next)
+	}
+	if workloads.namespace != "payments" {
+		t.Errorf("namespace = %q, want payments", workloads.namespace)
+	}
+	if back, _ := press(workloads, "q"); back != m {
+		t.Errorf("q returned %T, want the namespace list", back)
+	}
+}
+
+func TestAllPodsListsNamespace(t *testing.T) {
+	client := newTestClient(t)
+	m := buildWorkloadModel(client, "payments", nil)
+	next, _ := press(m, "enter")
+	pods, ok := next.(*PodsModel)
+	if !ok {
+		t.Fatalf("expected the pod list, got %T", next)
+	}
+	t.Cleanup(pods.watcher.Stop)
+	if got := len(pods.items.Items()); got != 2 {
+		t.Errorf("listed %d pods, want 2", got)
+	}
+}
+
+func TestHiddenNamespacesAreNotListed(t *testing.T) {
+	client := newTestClient(t)
+	HiddenNamespaces = []string{"kube-*"}
+	t.Cleanup(func() { HiddenNamespaces = nil })
+	m := BuildNamespaceModel(client, nil).(*namespacesModel)
+	var names []string
+	for _, item := range m.items.Items() {
+		names = append(names, item.(components.Item).Name)
+	}
+	if !slices.Equal(names, []string{"default", "payments"}) {
+		t.Errorf("namespaces = %v, want [default payments]", names)
+	}
+}
+
+func TestPodWithOneContainerOpensShell(t *testing.T) {
+	client := newTestClient(t)
+	m := buildPodModel(client, "payments", k8s.PodSelector{}, k8s.PodSelector{}, nil)
+	t.Cleanup(m.(*PodsModel).watcher.Stop)
+	m = moveTo(t, m, "worker-x2b1")
+	next, cmd := press(m, "enter")
+	if _, ok := next.(PodsModel); !ok {
+		t.Fatalf("expected to stay on the pod list, got %T", next)
+	}
+	next = runSession(t, next, cmd)
+
+	want := []fake.ExecCall{{Namespace: "payments", Pod: "worker-x2b1", Container: "worker", Command: []string{"bash"}}}
+	if !execsEqual(client.Execs, want) {
+		t.Errorf("execs = %v, want %v", client.Execs, want)
+	}
+	if status := next.(PodsModel).status; !strings.Contains(status, "payments/worker-x2b1/worker ended") {
+		t.Errorf("status = %q", status)
+	}
+}
+
+func TestPodWithSeveralContainersListsThem(t *testing.T) {
+	client := newTestClient(t)
+	m := buildPodModel(client, "payments", k8s.PodSelector{}, k8s.PodSelector{}, nil)
+	t.Cleanup(m.(*PodsModel).watcher.Stop)
+	m = moveTo(t, m, "api-7f9c")
+	next, _ := press(m, "enter")
+	containers, ok := next.(*ContainersModel)
+	if !ok {
+		t.Fatalf("expected the container list, got %T", next)
+	}
+	if containers.pod != "api-7f9c" || containers.namespace != "payments" {
+		t.Errorf("opened %s/%s, want payments/api-7f9c", containers.namespace, containers.pod)
+	}
+	if len(client.Execs) != 0 {
+		t.Errorf("execs = %v, want none", client.Execs)
+	}
+}
+
+func TestContainerEnterOpensShell(t *testing.T) {
+	client := newTestClient(t)
+	m := moveTo(t, buildContainerModel(client, "payments", "api-7f9c", nil), "sidecar")
+	next, cmd := press(m, "enter")
+	if got := next.(ContainersModel).GetContainer(); got != "sidecar" {
+		t.Errorf("container = %q, want sidecar", got)
+	}
+	runSession(t, next, cmd)
+
+	want := []fake.ExecCall{{Namespace: "payments", Pod: "api-7f9c", Container: "sidecar", Command: []string{"bash"}}}
+	if !execsEqual(client.Execs, want) {
+		t.Errorf("execs = %v, want %v", client.Execs, want)
+	}
+}
+
+func TestContainerShellFallsBack(t *testing.T) {
+	client := newTestClient(t)
+	client.ExecFunc = func(call fake.ExecCall) error {
+		if call.Command[0] == "bash" {
+			return &k8s.Error{Kind: k8s.KindExecutableNotFound, Op: "opening shell", Err: errors.New("bash not found")}
+		}
+		return nil
+	}
+	next, cmd := press(buildContainerModel(client, "payments", "api-7f9c", nil), "enter")
+	runSession(t, next, cmd)
+
+	var shells []string
+	for _, call := range client.Execs {
+		shells = append(shells, call.Command[0])
+	}
+	if !slices.Equal(shells, []string{"bash", "zsh"}) {
+		t.Errorf("tried %v, want [bash zsh]", shells)
+	}
+}
+
+func TestContainerQuitReturnsToPods(t *testing.T) {
+	client := newTestClient(t)
+	pods := buildPodModel(client, "payments", k8s.PodSelector{}, k8s.PodSelector{}, nil)
+	t.Cleanup(pods.(*PodsModel).watcher.Stop)
+	m := buildContainerModel(client, "payments", "api-7f9c", pods)
+	if back, _ := press(m, "q"); back != pods {
+		t.Errorf("q returned %T, want the pod list", back)
+	}
+}
+
+func TestContainerNotRunningShowsError(t *testing.T) {
+	client := newTestClient(t)
+	pod := runningPod("payments", "crashing", "app")
+	pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	if _, err := client.Clientset.CoreV1().Pods("payments").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	next, _ := press(buildContainerModel(client, "payments", "crashing", nil), "enter")
+	if _, ok := next.(*errorModel); !ok {
+		t.Fatalf("expected the error view, got %T", next)
+	}
+	if len(client.Execs) != 0 {
+		t.Errorf("execs = %v, want none", client.Execs)
+	}
+}
+
+func execsEqual(a []fake.ExecCall, b []fake.ExecCall) bool {
+	return slices.EqualFunc(a, b, func(x fake.ExecCall, y fake.ExecCall) bool {
+		return x.Namespace == y.Namespace && x.Pod == y.Pod && x.Container == y.Container && slices.Equal(x.Command, y.Command)
+	})
+}
+
+func TestDebugAttachesToDebugger(t *testing.T) {
+	client := newTestClient(t)
+	m := buildPodModel(client, "payments", k8s.PodSelector{}, k8s.PodSelector{}, nil)
+	t.Cleanup(m.(*PodsModel).watcher.Stop)
+	m = moveTo(t, m, "worker-x2b1")
+
+	// a second debugger still targets the pod's only regular container,
+	// not the first debugger
+	for i := 0; i < 2; i++ {
+		next, cmd := press(m, "D")
+		msg, ok := cmd().(debugStartedMsg)
+		if !ok || msg.err != nil {
+			t.Fatalf("debug container not started: %+v", msg)
+		}
+		next, cmd = next.Update(msg)
+		m = runSession(t, next, cmd)
+	}
+
+	pod, err := client.Pod("payments", "worker-x2b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.TargetContainerName != "worker" {
+			t.Errorf("%s targets %q, want worker", c.Name, c.TargetContainerName)
+		}
+	}
+	want := []fake.ExecCall{
+		{Namespace: "payments", Pod: "worker-x2b1", Container: "debugger-0"},
+		{Namespace: "payments", Pod: "worker-x2b1", Container: "debugger-1"},
+	}
+	if !execsEqual(client.Attaches, want) {
+		t.Errorf("attaches = %v, want %v", client.Attaches, want)
+	}
+	if len(client.Execs) != 0 {
+		t.Errorf("execs = %v, want none", client.Execs)
+	}
+}
+
+func TestSessionPausesOnlyOnFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		command []string
+		pause   bool
+	}{
+		{name: "shell exits", pause: false},
+		{name: "shell exits with last code", err: utilexec.CodeExitError{Err: errors.New("exit 1"), Code: 1}, pause: false},
+		{name: "shell fails", err: errors.New("connection reset"), pause: true},
+		{name: "command without tty", command: []string{"env"}, pause: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			client.ExecFunc = func(fake.ExecCall) error { return tt.err }
+			var out strings.Builder
+			c := &sessionCommand{client: client, session: k8s.Session{Namespace: "payments", Pod: "worker-x2b1", Container: "worker", Command: tt.command}}
+			c.SetStdin(strings.NewReader("\n"))
+			c.SetStdout(&out)
+			_ = c.Run()
+			if paused := strings.Contains(out.String(), "press enter"); paused != tt.pause {
+				t.Errorf("paused = %v, want %v; output %q", paused, tt.pause, out.String())
+			}
+		})
+	}
+}
+
+// owned returns a reference to the object as its controller.
+func owned(kind string, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func TestPinFollowsOwners(t *testing.T) {
+	tests := []struct {
+		name   string
+		owner  []metav1.OwnerReference
+		parent runtime.Object
+		want   string
+	}{
+		{name: "bare pod", want: "payments/shop-0*/app"},
+		{name: "deployment", owner: owned("ReplicaSet", "shop-5d8f"),
+			parent: &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "shop-5d8f", OwnerReferences: owned("Deployment", "shop")}},
+			want:   "payments/Deployment/shop/app"},
+		{name: "replica set of another controller", owner: owned("ReplicaSet", "shop-5d8f"),
+			parent: &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "shop-5d8f", OwnerReferences: owned("Rollout", "shop")}},
+			want:   "payments/shop-0*/app"},
+		{name: "replica set", owner: owned("ReplicaSet", "shop-5d8f"),
+			parent: &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "shop-5d8f"}},
+			want:   "payments/ReplicaSet/shop-5d8f/app"},
+		{name: "cron job", owner: owned("Job", "shop-28712"),
+			parent: &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "shop-28712", OwnerReferences: owned("CronJob", "shop")}},
+			want:   "payments/CronJob/shop/app"},
+		{name: "stateful set", owner: owned("StatefulSet", "shop"), want: "payments/StatefulSet/shop/app"},
+		{name: "other controller", owner: owned("Rollout", "shop"), want: "payments/shop-0*/app"},
+		{name: "owner gone", owner: owned("ReplicaSet", "shop-5d8f"), want: "payments/shop-0*/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			pod := runningPod("payments", "shop-0", "app")
+			pod.OwnerReferences = tt.owner
+			objects := []runtime.Object{pod}
+			if tt.parent != nil {
+				objects = append(objects, tt.parent)
+			}
+			for _, o := range objects {
+				if err := client.Clientset.Tracker().Add(o); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			m, cmd := press(buildContainerModel(client, "payments", "shop-0", nil), "p")
+			if cmd == nil {
+				t.Fatal("p returned no command")
+			}
+			if launches, _ := k8s.LoadLaunches(); len(launches.Favourites) != 0 {
+				t.Fatalf("pinned %v before the command ran", launches.Favourites)
+			}
+			next, _ := m.Update(cmd())
+			launches, err := k8s.LoadLaunches()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(launches.Favourites) != 1 {
+				t.Fatalf("favourites = %v, want one", launches.Favourites)
+			}
+			if got := launches.Favourites[0].Target(); got != tt.want {
+				t.Errorf("pinned %s, want %s", got, tt.want)
+			}
+			if !strings.Contains(next.View(), "pinned "+tt.want) {
+				t.Errorf("status does not report the pin:\n%s", next.View())
+			}
+		})
+	}
+}
+
+// protect makes the current context a protected one.
+func protect(t *testing.T) {
+	t.Helper()
+	kubeconfig := "apiVersion: v1\nkind: Config\ncurrent-context: prod\ncontexts:\n- name: prod\n  context:\n    cluster: prod\nclusters:\n- name: prod\n  cluster:\n    server: https://prod.example.com\n"
+	if err := os.WriteFile(os.Getenv("KUBECONFIG"), []byte(kubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	settings := k8s.ContextSettings
+	k8s.ContextSettings = []k8s.ContextDefaults{{Pattern: "prod", Protected: true}}
+	t.Cleanup(func() { k8s.ContextSettings = settings })
+}
+
+func TestProtectedContextAsksBeforeChanges(t *testing.T) {
+	client := newTestClient(t)
+	protect(t)
+
+	m := buildPodModel(client, "payments", k8s.PodSelector{}, k8s.PodSelector{}, nil)
+	t.Cleanup(m.(*PodsModel).watcher.Stop)
+	m = moveTo(t, m, "worker-x2b1")
+	next, cmd := press(m, "D")
+	msg, ok := cmd().(debugStartedMsg)
+	if !ok || !errors.Is(msg.err, k8s.ErrNotConfirmed) {
+		t.Fatalf("debug container started without asking: %+v", msg)
+	}
+	next, _ = next.Update(msg)
+	if view := next.View(); !strings.Contains(view, "debug container in worker-x2b1 cancelled") {
+		t.Errorf("view does not show the debug container cancelled:\n%s", view)
+	}
+	pod, err := client.Pod("payments", "worker-x2b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pod.Spec.EphemeralContainers) != 0 {
+		t.Errorf("ephemeral containers = %v, want none", pod.Spec.EphemeralContainers)
+	}
+
+	forward, ok := startForward(client, "payments", "worker-x2b1", 8080, 80)().(forwardStartedMsg)
+	if !ok || !errors.Is(forward.err, k8s.ErrNotConfirmed) || forward.forward != nil {
+		t.Errorf("port forwarded without asking: %+v", forward)
+	}
+
+	c := k8s.Copy{Namespace: "payments", Pod: "worker-x2b1", Container: "worker"}
+	job, cmd := startCopy(client, c, true, filepath.Join(t.TempDir(), "missing"), "/tmp")
+	if _, ok := cmd().(copyTickMsg); !ok {
+		t.Fatal("upload did not report back")
+	}
+	<-job.done
+	if !errors.Is(job.err, k8s.ErrNotConfirmed) {
+		t.Errorf("upload err = %v, want it not confirmed", job.err)
+	}
+	if len(client.Runs) != 0 || len(client.Execs) != 0 {
+		t.Errorf("runs = %v, execs = %v, want none", client.Runs, client.Execs)
+	}
+}
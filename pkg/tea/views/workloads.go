@@ -0,0 +1,91 @@
+package views
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samox73/ksh/pkg/k8s"
+	"github.com/samox73/ksh/pkg/tea/components"
+	"github.com/samox73/ksh/pkg/tea/styles"
+	"github.com/samox73/ksh/pkg/tea/utils"
+)
+
+const workloadBanner = `
+██╗    ██╗ ██████╗ ██████╗ ██╗  ██╗██╗      ██████╗  █████╗ ██████╗ ███████╗
+██║    ██║██╔═══██╗██╔══██╗██║ ██╔╝██║     ██╔═══██╗██╔══██╗██╔══██╗██╔════╝
+██║ █╗ ██║██║   ██║██████╔╝█████╔╝ ██║     ██║   ██║███████║██║  ██║███████╗
+██║███╗██║██║   ██║██╔══██╗██╔═██╗ ██║     ██║   ██║██╔══██║██║  ██║╚════██║
+╚███╔███╔╝╚██████╔╝██║  ██║██║  ██╗███████╗╚██████╔╝██║  ██║██████╔╝███████║
+ ╚══╝╚══╝  ╚═════╝ ╚═╝  ╚═╝╚═╝  ╚═╝╚══════╝ ╚═════╝ ╚═╝  ╚═╝╚═════╝ ╚══════╝`
+
+// workloadsModel lists the workloads of a namespace and opens the pods of
+// the selected one.
+type workloadsModel struct {
+	items     list.Model
+	workloads map[string]k8s.Workload
+	client    k8s.Client
+	namespace string
+	parent    tea.Model
+}
+
+func (m workloadsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *workloadsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.items.SetWidth(msg.Width)
+		m.items.SetHeight(utils.MinInt(msg.Height-lipgloss.Height(workloadBanner)-2, len(m.items.Items())+7))
+		return m, nil
+	case tea.KeyMsg:
+		switch keypress := keyOf(msg); keypress {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "q":
+			return switchTo(m.parent)
+		case "enter":
+			i, ok := m.items.SelectedItem().(components.Item)
+			if ok {
+				var selector k8s.PodSelector
+				if w, ok := m.workloads[i.Name]; ok {
+					selector = w.Pods()
+				}
+				return switchTo(buildPodModel(m.client, m.namespace, selector, k8s.PodSelector{}, m))
+			}
+		case "P":
+			if m.items.FilterState() != list.Filtering {
+				return switchTo(newForwardsModel(m))
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.items, cmd = m.items.Update(msg)
+	return m, cmd
+}
+
+func (m *workloadsModel) View() string {
+	banner := lipgloss.NewStyle().Margin(2, 0, 0, 2).Render(styles.GetBanner(workloadBanner))
+	context := utils.ViewContext()
+	return lipgloss.JoinVertical(lipgloss.Left, banner, context, m.items.View())
+}
+
+func buildWorkloadModel(client k8s.Client, namespace string, parent tea.Model) tea.Model {
+	retry := func() (tea.Model, tea.Cmd) { return switchTo(buildWorkloadModel(client, namespace, parent)) }
+	workloads, err := client.Workloads(namespace)
+	if err != nil {
+		return newErrorModel(err, retry, parent)
+	}
+	m := &workloadsModel{
+		items:     utils.BuildWorkloadList(workloads),
+		workloads: map[string]k8s.Workload{},
+		client:    client,
+		namespace: namespace,
+		parent:    parent,
+	}
+	for _, w := range workloads {
+		m.workloads[utils.WorkloadName(w)] = w
+	}
+	return m
+}